@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/SkycoinPro/skywire-services-uptime/src/database/postgres"
+	node_checker "github.com/SkycoinPro/skywire-services-uptime/src/node-checker"
+	log "github.com/sirupsen/logrus"
+)
+
+// runCommand executes one of the maintenance commands instead of starting the server
+func runCommand(name string, args []string) {
+	switch name {
+	case "rebuild":
+		rebuildCommand(args)
+	case "eligibility":
+		eligibilityCommand(args)
+	case "payout":
+		payoutCommand(args)
+	case "reconcile":
+		reconcileCommand(args)
+	case "verify-chain":
+		verifyChainCommand(args)
+	case "compact":
+		compactCommand(args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q, available commands: rebuild, eligibility, payout, reconcile, verify-chain, compact, verify\n", name)
+		os.Exit(2)
+	}
+}
+
+func rebuildCommand(args []string) {
+	flags := flag.NewFlagSet("rebuild", flag.ExitOnError)
+	schema := flags.String("schema", "uptime_rebuild", "fresh schema into which snapshots are replayed")
+	source := flags.String("source", "public", "schema from which maintenance windows and uptime adjustments are copied")
+	flags.Parse(args)
+	if *source == *schema {
+		log.Fatalf("Source schema has to differ from the rebuilt schema %v", *schema)
+	}
+
+	tearDown := postgres.InitSchema(*schema)
+	defer tearDown()
+
+	if err := node_checker.Rebuild(*source); err != nil {
+		log.Fatalf("Rebuild failed - %v", err)
+	}
+	fmt.Printf("snapshots replayed into schema %s\n", *schema)
+}
+
+func eligibilityCommand(args []string) {
+	flags := flag.NewFlagSet("eligibility", flag.ExitOnError)
+	policy := flags.String("policy", "default", "name of the eligibility policy")
+	month := flags.String("month", "", "evaluated month in YYYY-MM format, previous month when empty")
+	timezone := flags.String("timezone", "", "IANA timezone in which the month is cut, configured one when empty")
+	format := flags.String("format", node_checker.ExportFormatCSV, "output format, csv or json")
+	out := flags.String("out", "", "output file, standard output when empty")
+	flags.Parse(args)
+
+	tearDown := postgres.Init()
+	defer tearDown()
+
+	w := os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("Unable to create %v - %v", *out, err)
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := node_checker.ExportEligibility(*policy, *month, *timezone, *format, w); err != nil {
+		log.Fatalf("Eligibility export failed - %v", err)
+	}
+}
+
+func payoutCommand(args []string) {
+	flags := flag.NewFlagSet("payout", flag.ExitOnError)
+	var request node_checker.PayoutRequest
+	flags.StringVar(&request.Budget, "budget", "", "amount of coins to distribute")
+	flags.StringVar(&request.Policy, "policy", "default", "name of the eligibility policy")
+	flags.StringVar(&request.Scheme, "scheme", node_checker.PayoutSchemeUptime, "weighting scheme, equal, uptime-weighted or tiered")
+	flags.StringVar(&request.Month, "month", "", "paid month in YYYY-MM format, previous month when empty")
+	flags.StringVar(&request.Timezone, "timezone", "", "IANA timezone in which the month is cut, configured one when empty")
+	out := flags.String("out", "payout.csv", "csv file with amounts per node")
+	batch := flags.String("batch", "payout-batch.csv", "skycoin CLI batch file with amounts per owner address")
+	createdBy := flags.String("by", os.Getenv("USER"), "name recorded as the creator of the payout run")
+	flags.Parse(args)
+	if *createdBy == "" {
+		log.Fatal("Creator of the payout run has to be set by -by")
+	}
+
+	tearDown := postgres.Init()
+	defer tearDown()
+
+	run, err := node_checker.CreatePayout(request, *createdBy)
+	if err != nil {
+		log.Fatalf("Payout failed - %v", err)
+	}
+	for path, format := range map[string]string{*out: node_checker.ExportFormatCSV, *batch: node_checker.ExportFormatSkycoin} {
+		if err := writeFile(path, func(w io.Writer) error { return node_checker.WritePayout(w, run, format) }); err != nil {
+			log.Fatalf("Unable to write %v - %v", path, err)
+		}
+	}
+	fmt.Printf("payout run %d stored, per node amounts written to %s and batch file to %s\n", run.Id, *out, *batch)
+}
+
+func reconcileCommand(args []string) {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	var options node_checker.ReconcileOptions
+	left := flags.String("left", "", "first source, csv:path, live:YYYY-MM or monthly:YYYY-MM")
+	right := flags.String("right", "", "second source, csv:path, live:YYYY-MM or monthly:YYYY-MM")
+	flags.StringVar(&options.Field, "field", node_checker.ReconcileFieldUptime, "compared field, uptime, downtime or percentage")
+	flags.Float64Var(&options.Tolerance, "tolerance", 0, "absolute difference which is not reported")
+	flags.StringVar(&options.Timezone, "timezone", "", "IANA timezone in which live months are cut, configured one when empty")
+	flags.StringVar(&options.KeyColumn, "key-column", "key", "header name or zero based index of node key column in csv sources")
+	flags.StringVar(&options.ValueColumn, "value-column", "", "header name or zero based index of compared column in csv sources, field name when empty")
+	out := flags.String("out", "", "json diff report file, standard output when empty")
+	flags.Parse(args)
+
+	tearDown := postgres.Init()
+	defer tearDown()
+
+	report, err := node_checker.Reconcile(*left, *right, options)
+	if err != nil {
+		log.Fatalf("Reconciliation failed - %v", err)
+	}
+	write := func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	if *out == "" {
+		err = write(os.Stdout)
+	} else {
+		err = writeFile(*out, write)
+	}
+	if err != nil {
+		log.Fatalf("Unable to write reconciliation report - %v", err)
+	}
+	if !report.Consistent() {
+		fmt.Fprintf(os.Stderr, "%d missing, %d extra and %d differing nodes\n", len(report.Missing), len(report.Extra), len(report.Deltas))
+		tearDown()
+		os.Exit(1)
+	}
+}
+
+func verifyChainCommand(args []string) {
+	flags := flag.NewFlagSet("verify-chain", flag.ExitOnError)
+	head := flags.String("head", "", "previously seen chain head which the chain has to contain")
+	flags.Parse(args)
+
+	tearDown := postgres.Init()
+	defer tearDown()
+
+	verification, err := node_checker.VerifyReportChain(*head)
+	if err != nil {
+		log.Fatalf("Verification failed - %v", err)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(verification); err != nil {
+		log.Fatalf("Unable to write verification report - %v", err)
+	}
+	if !verification.Valid {
+		fmt.Fprintf(os.Stderr, "report chain is broken, %d problems found\n", len(verification.Problems))
+		tearDown()
+		os.Exit(1)
+	}
+}
+
+func compactCommand(args []string) {
+	flags := flag.NewFlagSet("compact", flag.ExitOnError)
+	flags.Parse(args)
+
+	tearDown := postgres.Init()
+	defer tearDown()
+
+	compaction, err := node_checker.CompactUptimes()
+	if err != nil {
+		log.Fatalf("Compaction failed - %v", err)
+	}
+	if compaction.Id == 0 {
+		fmt.Println("nothing to compact")
+		return
+	}
+	fmt.Printf("%d raw uptimes which ended before %v compacted into rollups\n", compaction.RowCount, compaction.CompactedBefore)
+	if compaction.ArchivePath != "" {
+		fmt.Printf("removed rows archived to %v\n", compaction.ArchivePath)
+	}
+}
+
+func verifyCommand(args []string) {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	file := flags.String("file", "", "exported response body")
+	signature := flags.String("signature", "", "value of the X-Signature header of the export")
+	publicKey := flags.String("pubkey", "", "public key of the service, see /api/v1/info/signingKey")
+	flags.Parse(args)
+
+	content, err := ioutil.ReadFile(*file)
+	if err != nil {
+		log.Fatalf("Unable to read export - %v", err)
+	}
+	if err := node_checker.VerifyExport(content, *signature, *publicKey); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println("signature is valid")
+}
+
+// writeFile creates the file and fills it by the write function
+func writeFile(path string, write func(w io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
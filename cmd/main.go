@@ -1,11 +1,12 @@
 package main
 
 import (
+	"os"
+
 	"github.com/SkycoinPro/skywire-services-uptime/src/app"
 	"github.com/SkycoinPro/skywire-services-uptime/src/config"
 	"github.com/SkycoinPro/skywire-services-uptime/src/database/postgres"
 	node_checker "github.com/SkycoinPro/skywire-services-uptime/src/node-checker"
-	"github.com/spf13/viper"
 )
 
 // @title Skywire User System API
@@ -14,16 +15,31 @@ import (
 
 // @host localhost:8080
 // @BasePath /api/v1
+
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
 func main() {
+	// exports are verified offline, without configuration and database
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		verifyCommand(os.Args[2:])
+		return
+	}
+
 	config.Init("node-checker-config")
 
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1], os.Args[2:])
+		return
+	}
+
 	tearDown := postgres.Init()
 	defer tearDown()
+	stopElection := postgres.StartElection()
+	defer stopElection()
 
 	uc := node_checker.DefaultController()
-	if viper.GetBool("config.make-uptimes-for-previous-months") {
-		go uc.GetUptimesForPreviousMonths()
-	}
+	go uc.RollupRoutine()
 	go uc.RunningRoutine()
 	// register all of the controllers here
 	app.NewServer(
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS node_events (
+    id SERIAL PRIMARY KEY,
+    node_id VARCHAR(255) NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    detected_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    run_id INTEGER REFERENCES collection_runs (id),
+    created_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS node_events_node_id_idx ON node_events (node_id, occurred_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS closed_months;
+DROP INDEX IF EXISTS monthly_uptimes_node_year_month_idx;
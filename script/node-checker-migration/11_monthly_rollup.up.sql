@@ -0,0 +1,14 @@
+DELETE FROM monthly_uptimes a USING monthly_uptimes b
+    WHERE a.node_id = b.node_id AND a.year = b.year AND a.month = b.month AND a.id < b.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS monthly_uptimes_node_year_month_idx ON monthly_uptimes (node_id, year, month);
+
+CREATE TABLE IF NOT EXISTS closed_months (
+    id SERIAL PRIMARY KEY,
+    year INTEGER NOT NULL,
+    month INTEGER NOT NULL,
+    closed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (year, month)
+);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS uptime_rollups (
+    id SERIAL PRIMARY KEY,
+    node_id VARCHAR(255) NOT NULL,
+    granularity VARCHAR(16) NOT NULL,
+    bucket_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    uptime_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (node_id, granularity, bucket_start)
+);
+
+CREATE INDEX IF NOT EXISTS uptime_rollups_bucket_idx ON uptime_rollups (granularity, bucket_start);
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS payout_runs (
+    id SERIAL PRIMARY KEY,
+    scheme VARCHAR(32) NOT NULL,
+    policy VARCHAR(255) NOT NULL,
+    start_date TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_date TIMESTAMP WITH TIME ZONE NOT NULL,
+    budget BIGINT NOT NULL,
+    distributed BIGINT NOT NULL,
+    precision INTEGER NOT NULL,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS payout_entries (
+    id SERIAL PRIMARY KEY,
+    run_id INTEGER NOT NULL REFERENCES payout_runs (id) ON DELETE CASCADE,
+    node_id VARCHAR(255) NOT NULL,
+    address VARCHAR(255) NOT NULL DEFAULT '',
+    weight BIGINT NOT NULL,
+    amount BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS payout_entries_run_id_idx ON payout_entries (run_id);
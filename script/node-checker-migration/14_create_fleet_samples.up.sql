@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS fleet_samples (
+    id SERIAL PRIMARY KEY,
+    run_id INTEGER,
+    sampled_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    total INTEGER NOT NULL,
+    online INTEGER NOT NULL,
+    offline INTEGER NOT NULL,
+    new_nodes INTEGER NOT NULL,
+    went_offline INTEGER NOT NULL,
+    restarted INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS fleet_samples_sampled_at_idx ON fleet_samples (sampled_at);
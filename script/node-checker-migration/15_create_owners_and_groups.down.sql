@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS group_nodes;
+DROP TABLE IF EXISTS node_groups;
+DROP TABLE IF EXISTS owner_nodes;
+DROP TABLE IF EXISTS owners;
@@ -0,0 +1,2 @@
+ALTER TABLE monthly_uptimes DROP COLUMN IF EXISTS excused;
+DROP TABLE IF EXISTS maintenance_windows;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS maintenance_windows (
+    id SERIAL PRIMARY KEY,
+    node_key VARCHAR(255),
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ended_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    reason TEXT NOT NULL,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    CHECK (ended_at > started_at)
+);
+
+CREATE INDEX IF NOT EXISTS maintenance_windows_period_idx ON maintenance_windows (started_at, ended_at);
+CREATE INDEX IF NOT EXISTS maintenance_windows_node_key_idx ON maintenance_windows (node_key);
+
+ALTER TABLE monthly_uptimes ADD COLUMN IF NOT EXISTS excused INTEGER NOT NULL DEFAULT 0;
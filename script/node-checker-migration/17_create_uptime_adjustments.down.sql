@@ -0,0 +1,2 @@
+ALTER TABLE monthly_uptimes DROP COLUMN IF EXISTS adjusted;
+DROP TABLE IF EXISTS uptime_adjustments;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS uptime_adjustments (
+    id SERIAL PRIMARY KEY,
+    node_key VARCHAR(255) NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    seconds BIGINT NOT NULL,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ended_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    reason TEXT NOT NULL,
+    ticket VARCHAR(255) NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    reverted_by VARCHAR(255),
+    reverted_at TIMESTAMP WITH TIME ZONE,
+    revert_reason TEXT,
+    CHECK (kind IN ('credit', 'debit')),
+    CHECK (seconds > 0),
+    CHECK (ended_at > started_at)
+);
+
+CREATE INDEX IF NOT EXISTS uptime_adjustments_node_key_idx ON uptime_adjustments (node_key);
+CREATE INDEX IF NOT EXISTS uptime_adjustments_period_idx ON uptime_adjustments (started_at, ended_at);
+
+ALTER TABLE monthly_uptimes ADD COLUMN IF NOT EXISTS adjusted INTEGER NOT NULL DEFAULT 0;
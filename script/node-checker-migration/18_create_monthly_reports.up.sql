@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS monthly_reports (
+    id SERIAL PRIMARY KEY,
+    year INTEGER NOT NULL,
+    month INTEGER NOT NULL,
+    revision INTEGER NOT NULL,
+    timezone VARCHAR(64) NOT NULL,
+    report TEXT NOT NULL,
+    report_hash CHAR(64) NOT NULL,
+    previous_hash CHAR(64) NOT NULL UNIQUE,
+    hash CHAR(64) NOT NULL UNIQUE,
+    published_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE (year, month, revision)
+);
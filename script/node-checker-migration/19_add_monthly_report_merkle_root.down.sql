@@ -0,0 +1 @@
+ALTER TABLE monthly_reports DROP COLUMN IF EXISTS merkle_root;
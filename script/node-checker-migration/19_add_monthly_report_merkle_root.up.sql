@@ -0,0 +1 @@
+ALTER TABLE monthly_reports ADD COLUMN IF NOT EXISTS merkle_root CHAR(64) NOT NULL DEFAULT '';
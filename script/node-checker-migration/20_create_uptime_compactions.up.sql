@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS uptime_compactions (
+    id SERIAL PRIMARY KEY,
+    compacted_before TIMESTAMP WITH TIME ZONE NOT NULL UNIQUE,
+    row_count BIGINT NOT NULL,
+    archive_path TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS heartbeat_nonces;
+ALTER TABLE nodes DROP COLUMN IF EXISTS last_heartbeat;
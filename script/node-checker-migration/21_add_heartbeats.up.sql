@@ -0,0 +1,10 @@
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS last_heartbeat TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS heartbeat_nonces (
+    node_key VARCHAR(255) NOT NULL,
+    nonce VARCHAR(255) NOT NULL,
+    sent_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (node_key, nonce)
+);
+
+CREATE INDEX IF NOT EXISTS heartbeat_nonces_sent_at_idx ON heartbeat_nonces (sent_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS uptimes_node_id_created_at_idx;
+DROP INDEX IF EXISTS uptimes_created_at_idx;
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS uptimes_created_at_idx ON uptimes (created_at);
+CREATE INDEX IF NOT EXISTS uptimes_node_id_created_at_idx ON uptimes (node_id, created_at);
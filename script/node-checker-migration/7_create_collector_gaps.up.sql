@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS collector_gaps (
+    id SERIAL PRIMARY KEY,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ended_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS collector_gaps_started_at_idx ON collector_gaps (started_at);
+CREATE INDEX IF NOT EXISTS collector_gaps_ended_at_idx ON collector_gaps (ended_at);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS collection_runs (
+    id SERIAL PRIMARY KEY,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    source VARCHAR(255) NOT NULL DEFAULT '',
+    http_status INTEGER NOT NULL DEFAULT 0,
+    nodes_received INTEGER NOT NULL DEFAULT 0,
+    nodes_skipped INTEGER NOT NULL DEFAULT 0,
+    new_nodes INTEGER NOT NULL DEFAULT 0,
+    restarts INTEGER NOT NULL DEFAULT 0,
+    nodes_marked_offline INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS collection_runs_started_at_idx ON collection_runs (started_at);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS node_metadata (
+    id SERIAL PRIMARY KEY,
+    node_id VARCHAR(255) NOT NULL,
+    attribute VARCHAR(255) NOT NULL,
+    value TEXT NOT NULL DEFAULT '',
+    valid_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    valid_to TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS node_metadata_node_id_idx ON node_metadata (node_id, attribute, valid_from);
+CREATE UNIQUE INDEX IF NOT EXISTS node_metadata_current_idx ON node_metadata (node_id, attribute) WHERE valid_to IS NULL;
@@ -17,14 +17,62 @@ import (
 
 var DB *gorm.DB
 
+// Elector decides which of the instances connected to the database collects the data
+var Elector *LeaderElector
+
 // Init creates a connection to database
 func Init() func() {
+	connect(dBInfo())
+
+	return func() {
+		log.Error("Disconnecting database")
+		DB.Close()
+		log.Debug("Database disconnected")
+	}
+}
+
+// StartElection starts competing for the leadership with other instances serving the API, it has to be called
+// after Init and only by the server, so that commands never hold the leadership
+func StartElection() func() {
+	Elector = NewLeaderElector(DB.DB(),
+		viper.GetInt64("leader-election.lock-id"),
+		viper.GetDuration("leader-election.interval"),
+		!viper.GetBool("leader-election.enabled"))
+	go Elector.Run()
+
+	return func() {
+		Elector.Stop()
+	}
+}
+
+// InitSchema creates a connection to the given schema of the database, creating and migrating it if needed
+func InitSchema(schema string) func() {
+	admin, err := gorm.Open("postgres", dBInfo())
+	if err != nil {
+		log.Fatalf("Failed to connect to database %v", err)
+	}
+	if err := admin.Exec(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %q", schema)).Error; err != nil {
+		log.Fatalf("Unable to create schema %v - %v", schema, err)
+	}
+	admin.Close()
+
+	connect(fmt.Sprintf("%s search_path=%s", dBInfo(), schema))
+	log.Infof("Using schema %v", schema)
+
+	return func() {
+		log.Error("Disconnecting database")
+		DB.Close()
+		log.Debug("Database disconnected")
+	}
+}
+
+func connect(dbInfo string) {
 	var err error
-	DB, err = gorm.Open("postgres", dBInfo())
-	DB.LogMode(viper.GetBool("database.log-mode"))
+	DB, err = gorm.Open("postgres", dbInfo)
 	if err != nil {
 		log.Fatalf("Failed to connect to database %v", err)
 	}
+	DB.LogMode(viper.GetBool("database.log-mode"))
 	log.Info("Database connected")
 
 	log.Info("Migration process started")
@@ -40,16 +88,10 @@ func Init() func() {
 		if strings.Contains(err.Error(), "no change") {
 			log.Info("Nothing to migrate")
 		} else {
-			log.Fatalf("Unable to migrate to the latest db version", err)
+			log.Fatalf("Unable to migrate to the latest db version %v", err)
 		}
 	}
 	log.Info("Migration process finished")
-
-	return func() {
-		log.Error("Disconnecting database")
-		DB.Close()
-		log.Debug("Database disconnected")
-	}
 }
 
 func dBInfo() string {
@@ -68,7 +110,7 @@ func dBInfo() string {
 		database,
 		sslmode,
 	)
-	log.Debug("Prepared connection string for db %v", dbInfo)
+	log.Debugf("Prepared connection string for db %v", dbInfo)
 
 	return dbInfo
 }
@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// LeaderElector elects a single leader among instances connected to the same database.
+// Leadership is held through a session level advisory lock on a dedicated connection,
+// so it is released by postgres as soon as the leader's session dies.
+type LeaderElector struct {
+	db       *sql.DB
+	lockID   int64
+	interval time.Duration
+	disabled bool
+
+	conn   *sql.Conn    // connection holding the lock, used only by the election loop
+	leader atomic.Value // bool, read by IsLeader without waiting for queries of the election loop
+	stop   chan struct{}
+}
+
+// NewLeaderElector prepares elector which competes for the advisory lock with the given id.
+// Disabled elector always reports leadership which is suitable for single instance deployments.
+func NewLeaderElector(db *sql.DB, lockID int64, interval time.Duration, disabled bool) *LeaderElector {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	e := &LeaderElector{
+		db:       db,
+		lockID:   lockID,
+		interval: interval,
+		disabled: disabled,
+		stop:     make(chan struct{}),
+	}
+	e.leader.Store(false)
+	return e
+}
+
+// IsLeader reports whether this instance currently holds the leadership
+func (e *LeaderElector) IsLeader() bool {
+	if e.disabled {
+		return true
+	}
+	return e.leader.Load().(bool)
+}
+
+// Run keeps trying to acquire leadership and verifies that the held one is still valid until Stop is called
+func (e *LeaderElector) Run() {
+	if e.disabled {
+		log.Info("Leader election disabled, this instance is collecting data")
+		return
+	}
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		e.check()
+		select {
+		case <-ticker.C:
+		case <-e.stop:
+			e.release()
+			return
+		}
+	}
+}
+
+// Stop gives up the leadership and stops the election loop
+func (e *LeaderElector) Stop() {
+	if e.disabled {
+		return
+	}
+	close(e.stop)
+}
+
+func (e *LeaderElector) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.interval)
+	defer cancel()
+
+	if e.conn != nil {
+		_, err := e.conn.ExecContext(ctx, "SELECT 1")
+		if err == nil {
+			return
+		}
+		log.Errorf("Lost leadership, connection holding the advisory lock failed - %v", err)
+		e.drop()
+	}
+
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		log.Errorf("Unable to get connection for leader election - %v", err)
+		return
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", e.lockID).Scan(&acquired); err != nil {
+		log.Errorf("Unable to try advisory lock %v - %v", e.lockID, err)
+		discard(conn) // the lock might have been acquired before the query failed
+		return
+	}
+	if !acquired {
+		conn.Close()
+		return
+	}
+	log.Info("Acquired leadership, this instance is collecting data")
+	e.conn = conn
+	e.leader.Store(true)
+}
+
+func (e *LeaderElector) release() {
+	if e.conn == nil {
+		return
+	}
+	if _, err := e.conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", e.lockID); err != nil {
+		log.Errorf("Unable to release advisory lock %v - %v", e.lockID, err)
+		e.drop()
+		return
+	}
+	e.leader.Store(false)
+	e.conn.Close()
+	e.conn = nil
+	log.Info("Leadership released")
+}
+
+// drop gives up the leadership by closing the session holding the lock
+func (e *LeaderElector) drop() {
+	e.leader.Store(false)
+	discard(e.conn)
+	e.conn = nil
+}
+
+// discard closes the session of the connection instead of returning it to the pool, so that postgres releases
+// advisory locks held by it
+func discard(conn *sql.Conn) {
+	conn.Raw(func(driverConn interface{}) error {
+		if closer, ok := driverConn.(io.Closer); ok {
+			closer.Close()
+		}
+		return driver.ErrBadConn
+	})
+	conn.Close()
+}
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// testConfigEnv names the configuration file of the database used by tests which need postgres
+const testConfigEnv = "UPTIME_TEST_CONFIG"
+
+func openTestDB(t *testing.T) *sql.DB {
+	config := os.Getenv(testConfigEnv)
+	if config == "" {
+		t.Skipf("%v is not set", testConfigEnv)
+	}
+	viper.SetConfigFile(config)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatalf("Unable to read %v - %v", config, err)
+	}
+	db, err := sql.Open("postgres", dBInfo())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestLeaderElectorTakeover(t *testing.T) {
+	tests := []struct {
+		name string
+		lose func(t *testing.T, leader *LeaderElector, other *sql.DB)
+	}{
+		{
+			name: "lock session terminated",
+			lose: func(t *testing.T, leader *LeaderElector, other *sql.DB) {
+				var pid int
+				if err := leader.conn.QueryRowContext(context.Background(), "SELECT pg_backend_pid()").Scan(&pid); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := other.Exec("SELECT pg_terminate_backend($1)", pid); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "lock connection dropped",
+			lose: func(t *testing.T, leader *LeaderElector, other *sql.DB) {
+				leader.drop()
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			first, second := openTestDB(t), openTestDB(t)
+			lockID := time.Now().UnixNano()
+			leader := NewLeaderElector(first, lockID, time.Second, false)
+			follower := NewLeaderElector(second, lockID, time.Second, false)
+			defer leader.release()
+			defer follower.release()
+
+			leader.check()
+			follower.check()
+			if !leader.IsLeader() || follower.IsLeader() {
+				t.Fatalf("expected only the first elector to lead, got %v and %v", leader.IsLeader(), follower.IsLeader())
+			}
+
+			test.lose(t, leader, second)
+			follower.check()
+			if !follower.IsLeader() {
+				t.Fatal("expected the second elector to take over the leadership")
+			}
+			// neither the failed connection nor other pooled connections of the first elector may hold the lock
+			leader.check()
+			if leader.IsLeader() {
+				t.Fatal("expected the first elector to lose the leadership")
+			}
+		})
+	}
+}
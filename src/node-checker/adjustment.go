@@ -0,0 +1,135 @@
+package node_checker
+
+import (
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Kinds of uptime adjustments
+const (
+	AdjustmentCredit = "credit" // seconds are added to uptime
+	AdjustmentDebit  = "debit"  // seconds are taken from uptime
+)
+
+// adminToken authenticates an admin allowed to manage adjustments
+type adminToken struct {
+	Name  string `mapstructure:"name"`
+	Token string `mapstructure:"token"`
+}
+
+// adminTokens returns admins defined in the configuration
+func adminTokens() []adminToken {
+	var admins []adminToken
+	if err := viper.UnmarshalKey("admins", &admins); err != nil {
+		log.Errorf("Unable to read admins from configuration - %v", err)
+	}
+	return admins
+}
+
+// adjustedSeconds sums seconds of adjustments falling into the [startDate, endDate) period. An adjustment
+// is spread evenly over its own period, so only the share overlapping the requested period is applied.
+func adjustedSeconds(adjustments []UptimeAdjustment, startDate time.Time, endDate time.Time) float64 {
+	total := 0.0
+	for _, adjustment := range adjustments {
+		period := interval{Start: adjustment.StartedAt, End: adjustment.EndedAt}
+		overlap := intersectPeriod([]interval{period}, startDate, endDate)
+		if len(overlap) == 0 {
+			continue
+		}
+		share := float64(adjustment.Seconds) * overlap[0].duration().Seconds() / period.duration().Seconds()
+		if adjustment.Kind == AdjustmentDebit {
+			share = -share
+		}
+		total += share
+	}
+	return total
+}
+
+// activeAdjustments returns adjustments which are not reverted within the period mapped by node key
+func (ns *Service) activeAdjustments(startDate time.Time, endDate time.Time) (map[string][]UptimeAdjustment, error) {
+	adjustments, err := ns.db.findAdjustments(startDate, endDate, "", false)
+	if err != nil {
+		return nil, err
+	}
+	byNode := make(map[string][]UptimeAdjustment)
+	for _, adjustment := range adjustments {
+		byNode[adjustment.NodeKey] = append(byNode[adjustment.NodeKey], adjustment)
+	}
+	return byNode, nil
+}
+
+func (ns *Service) createAdjustment(request AdjustmentRequest, admin string) (UptimeAdjustment, error) {
+	adjustment := UptimeAdjustment{
+		NodeKey:   strings.TrimSpace(request.NodeKey),
+		Kind:      request.Kind,
+		Seconds:   request.Seconds,
+		StartedAt: request.StartedAt,
+		EndedAt:   request.EndedAt,
+		Reason:    strings.TrimSpace(request.Reason),
+		Ticket:    strings.TrimSpace(request.Ticket),
+		CreatedBy: admin,
+	}
+	if adjustment.NodeKey == "" || adjustment.Reason == "" || adjustment.Seconds <= 0 ||
+		(adjustment.Kind != AdjustmentCredit && adjustment.Kind != AdjustmentDebit) ||
+		adjustment.StartedAt.IsZero() || !adjustment.EndedAt.After(adjustment.StartedAt) ||
+		float64(adjustment.Seconds) > adjustment.EndedAt.Sub(adjustment.StartedAt).Seconds() {
+		return UptimeAdjustment{}, errInvalidAdjustment
+	}
+	if err := ns.db.createAdjustment(&adjustment); err != nil {
+		return UptimeAdjustment{}, err
+	}
+	log.Infof("Uptime adjustment %v of node %v: %v of %v seconds by %v", adjustment.Id, adjustment.NodeKey, adjustment.Kind, adjustment.Seconds, admin)
+	warnClosedMonths("Uptime adjustment", adjustment.Id, adjustment.StartedAt)
+	return adjustment, nil
+}
+
+// revertAdjustment stops applying the adjustment, it is kept with who reverted it and why
+func (ns *Service) revertAdjustment(id uint, request RevertAdjustmentRequest, admin string) (UptimeAdjustment, error) {
+	reason := strings.TrimSpace(request.Reason)
+	if reason == "" {
+		return UptimeAdjustment{}, errInvalidAdjustment
+	}
+	if _, err := ns.db.findAdjustment(id); err != nil {
+		return UptimeAdjustment{}, err
+	}
+	if err := ns.db.revertAdjustment(id, admin, reason, time.Now()); err != nil {
+		return UptimeAdjustment{}, err
+	}
+	adjustment, err := ns.db.findAdjustment(id)
+	if err != nil {
+		return UptimeAdjustment{}, err
+	}
+	log.Infof("Uptime adjustment %v of node %v reverted by %v", id, adjustment.NodeKey, admin)
+	warnClosedMonths("Uptime adjustment", adjustment.Id, adjustment.StartedAt)
+	return adjustment, nil
+}
+
+func (ns *Service) getAdjustment(id uint) (UptimeAdjustment, error) {
+	return ns.db.findAdjustment(id)
+}
+
+// getAdjustments returns adjustments overlapping the period including reverted ones, of every node when node key is empty
+func (ns *Service) getAdjustments(startDate time.Time, endDate time.Time, nodeKey string) ([]UptimeAdjustment, error) {
+	adjustments, err := ns.db.findAdjustments(startDate, endDate, strings.TrimSpace(nodeKey), true)
+	if err != nil {
+		return nil, errCannotLoadDataFromDatabase
+	}
+	return adjustments, nil
+}
+
+type AdjustmentRequest struct {
+	NodeKey   string    `json:"nodeKey" binding:"required"`
+	Kind      string    `json:"kind" binding:"required"`    // credit or debit
+	Seconds   int64     `json:"seconds" binding:"required"` // at most the length of the period
+	StartedAt time.Time `json:"startedAt" binding:"required"`
+	EndedAt   time.Time `json:"endedAt" binding:"required"`
+	Reason    string    `json:"reason" binding:"required"`
+	Ticket    string    `json:"ticket"`
+}
+
+type RevertAdjustmentRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
@@ -0,0 +1,112 @@
+package node_checker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SkycoinPro/skywire-services-uptime/src/database/postgres"
+	"github.com/spf13/viper"
+)
+
+// testConfigEnv names the configuration file of the database in which tests and benchmarks create throwaway schemas
+const testConfigEnv = "UPTIME_TEST_CONFIG"
+
+// staticNodeSource is a NodeSource which always returns the same prepared nodes
+type staticNodeSource struct {
+	name  string
+	nodes NodeResponse
+}
+
+func (s *staticNodeSource) Name() string {
+	return s.name
+}
+
+func (s *staticNodeSource) Fetch() (NodeResponse, []byte, error) {
+	return s.nodes, nil, nil
+}
+
+func (s *staticNodeSource) Health() SourceHealth {
+	return SourceHealth{Name: s.name, Healthy: true, LastNodeCount: len(s.nodes)}
+}
+
+func generatedNodes(prefix string, count int) NodeResponse {
+	nodes := make(NodeResponse, count)
+	for i := range nodes {
+		nodes[i] = NodeDef{Key: fmt.Sprintf("%s-%08d", prefix, i), StartTime: 3600}
+	}
+	return nodes
+}
+
+// benchConfig reads the configuration of the database named by testConfigEnv
+func benchConfig(b *testing.B) {
+	config := os.Getenv(testConfigEnv)
+	if config == "" {
+		b.Skipf("%v is not set", testConfigEnv)
+	}
+	viper.SetConfigFile(config)
+	if err := viper.ReadInConfig(); err != nil {
+		b.Fatalf("Unable to read %v - %v", config, err)
+	}
+	migrations, err := filepath.Abs(filepath.Join("..", "..", "script", "node-checker-migration"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	viper.Set("database.migration-source", "file://"+migrations)
+	viper.Set("database.log-mode", false)
+}
+
+// benchSchema connects to a new schema of the configured database and returns function which drops it
+func benchSchema(b *testing.B) func() {
+	schema := fmt.Sprintf("uptime_bench_%d", time.Now().UnixNano())
+	tearDown := postgres.InitSchema(schema)
+	return func() {
+		if err := postgres.DB.Exec(fmt.Sprintf("DROP SCHEMA %q CASCADE", schema)).Error; err != nil {
+			b.Errorf("Unable to drop schema %v - %v", schema, err)
+		}
+		tearDown()
+	}
+}
+
+// BenchmarkCollection measures a collection run which creates every node in an empty schema and one which extends
+// uptimes of known nodes. Run it with UPTIME_TEST_CONFIG pointing to a configuration file, e.g.
+// UPTIME_TEST_CONFIG=$PWD/config.toml go test -run NONE -bench Collection -benchtime 5x ./src/node-checker/
+func BenchmarkCollection(b *testing.B) {
+	benchConfig(b)
+	for _, count := range []int{1000, 10000, 50000} {
+		b.Run(fmt.Sprintf("create/nodes=%d", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				drop := benchSchema(b)
+				source := &staticNodeSource{name: "benchmark", nodes: generatedNodes("create", count)}
+				ns := NewService(DefaultData(), NewSources(MergeMaxStartTime, 0, source))
+				b.StartTimer()
+				if _, err := ns.updateNodeInfo(); err != nil {
+					b.Fatal(err)
+				}
+				b.StopTimer()
+				drop()
+			}
+		})
+		b.Run(fmt.Sprintf("extend/nodes=%d", count), func(b *testing.B) {
+			drop := benchSchema(b)
+			defer drop()
+			source := &staticNodeSource{name: "benchmark", nodes: generatedNodes("extend", count)}
+			ns := NewService(DefaultData(), NewSources(MergeMaxStartTime, 0, source))
+			if _, err := ns.updateNodeInfo(); err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for j := range source.nodes {
+					source.nodes[j].StartTime += 300
+				}
+				if _, err := ns.updateNodeInfo(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,347 @@
+package node_checker
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const (
+	uptimeArchiveExtension = ".jsonl.gz"
+	// uptimeArchiveMarker is appended to the archive path to mark archives whose uptimes were removed from the database
+	uptimeArchiveMarker = ".done"
+)
+
+// retentionMonths returns the number of closed months whose raw uptimes are kept, zero keeps them forever
+func retentionMonths() int {
+	if months := viper.GetInt("retention.months"); months > 0 {
+		return months
+	}
+	return 0
+}
+
+// retentionBoundary returns the time before which raw uptimes are not retained, the start of the oldest retained month.
+// It is the start of a daily rollup bucket, as daily buckets start at midnight in the reporting timezone.
+func retentionBoundary(currentTime time.Time, months int, location *time.Location) (YearMonth, time.Time) {
+	oldest := lastClosableMonth(currentTime, location)
+	for i := 1; i < months; i++ {
+		oldest = oldest.previous()
+	}
+	return oldest, oldest.start(location).UTC()
+}
+
+// compactionBoundary returns the time before which raw uptimes were replaced by rollups, zero time when they were not
+func (ns *Service) compactionBoundary() (time.Time, error) {
+	compaction, err := ns.db.findLastCompaction()
+	if err == errCannotLoadDataFromDatabase {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return compaction.CompactedBefore, nil
+}
+
+// rollupInterval places running time of the bucket at its start
+func rollupInterval(rollup UptimeRollup, location *time.Location) interval {
+	start := rollup.BucketStart.UTC()
+	end := start.Add(time.Duration(rollup.UptimeSeconds * float64(time.Second)))
+	if next := nextBucket(start, rollup.Granularity, location); end.After(next) {
+		end = next
+	}
+	return interval{Start: start, End: end}
+}
+
+// compactedIntervals reconstructs running intervals of the nodes within [startDate, boundary) from rollups, mapped by
+// node key. Hourly buckets are used for days which have them and daily buckets otherwise. Running time of a bucket is
+// placed at its start, so uptime of periods which do not start and end on bucket boundaries is approximate. Buckets
+// never cross month boundaries in the reporting timezone, so running time stays in its month.
+func (ns *Service) compactedIntervals(nodeKeys []string, startDate time.Time, boundary time.Time) (map[string][]interval, error) {
+	location := reportingLocation()
+	from := bucketStart(startDate, GranularityDay, location)
+	hours, err := ns.db.findUptimeRollups(nodeKeys, GranularityHour, from, boundary)
+	if err != nil {
+		return nil, err
+	}
+	days, err := ns.db.findUptimeRollups(nodeKeys, GranularityDay, from, boundary)
+	if err != nil {
+		return nil, err
+	}
+
+	hourly := make(map[string]map[time.Time]bool)
+	intervals := make(map[string][]interval)
+	for _, rollup := range hours {
+		if hourly[rollup.NodeId] == nil {
+			hourly[rollup.NodeId] = make(map[time.Time]bool)
+		}
+		hourly[rollup.NodeId][bucketStart(rollup.BucketStart, GranularityDay, location)] = true
+		intervals[rollup.NodeId] = append(intervals[rollup.NodeId], rollupInterval(rollup, location))
+	}
+	for _, rollup := range days {
+		if !hourly[rollup.NodeId][rollup.BucketStart.UTC()] {
+			intervals[rollup.NodeId] = append(intervals[rollup.NodeId], rollupInterval(rollup, location))
+		}
+	}
+	for key := range intervals {
+		intervals[key] = normalizeIntervals(intervals[key])
+	}
+	return intervals, nil
+}
+
+// withCompacted joins intervals reconstructed before the compaction boundary with raw ones after it
+func withCompacted(compacted []interval, raw []interval, boundary time.Time) []interval {
+	running := append([]interval(nil), compacted...)
+	for _, i := range raw {
+		if !i.End.After(boundary) {
+			continue
+		}
+		if i.Start.Before(boundary) {
+			i.Start = boundary
+		}
+		running = append(running, i)
+	}
+	return normalizeIntervals(running)
+}
+
+// runningIntervals returns intervals in which the node was running. When the period starts before the compaction
+// boundary, the time before it is reconstructed from rollups, which replaced raw uptimes there.
+func (ns *Service) runningIntervals(node Node, boundary time.Time, startDate time.Time) ([]interval, error) {
+	raw := uptimeIntervals(node.Uptimes)
+	if boundary.IsZero() || !startDate.Before(boundary) {
+		return raw, nil
+	}
+	compacted, err := ns.compactedIntervals([]string{node.Key}, startDate, boundary)
+	if err != nil {
+		return nil, err
+	}
+	return withCompacted(compacted[node.Key], raw, boundary), nil
+}
+
+// periodRunningIntervals returns intervals in which the nodes were running during [startDate, endDate), mapped by node
+// key, as runningIntervals does for a single node. Uptimes and rollups of all nodes are loaded in batches.
+func (ns *Service) periodRunningIntervals(nodes []Node, boundary time.Time, startDate time.Time, endDate time.Time) (map[string][]interval, error) {
+	uptimes, err := ns.db.findPeriodUptimes(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	running := make(map[string][]interval, len(uptimes))
+	for key, nodeUptimes := range uptimes {
+		running[key] = uptimeIntervals(nodeUptimes)
+	}
+	if boundary.IsZero() || !startDate.Before(boundary) {
+		return running, nil
+	}
+	keys := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		keys = append(keys, node.Key)
+	}
+	compacted, err := ns.compactedIntervals(keys, startDate, boundary)
+	if err != nil {
+		return nil, err
+	}
+	for key, intervals := range running {
+		running[key] = withCompacted(compacted[key], intervals, boundary)
+	}
+	for key, intervals := range compacted {
+		if _, ok := running[key]; !ok {
+			running[key] = intervals
+		}
+	}
+	return running, nil
+}
+
+// compactUptimes replaces raw uptimes which ended before the retained closed months by rollups. Rollups of the
+// compacted period are rebuilt from raw uptimes first, removed rows are archived when archive directory is configured.
+// Every month before the boundary has to be closed, so that its monthly uptimes were computed from raw uptimes.
+func (ns *Service) compactUptimes(currentTime time.Time) (UptimeCompaction, error) {
+	months := retentionMonths()
+	if months == 0 {
+		return UptimeCompaction{}, errRetentionDisabled
+	}
+	location := reportingLocation()
+	oldest, boundary := retentionBoundary(currentTime, months, location)
+	hourly := viper.GetBool("rollups.hourly")
+	previous, err := ns.compactionBoundary()
+	if err != nil {
+		return UptimeCompaction{}, errCannotLoadDataFromDatabase
+	}
+	if !boundary.After(previous) {
+		return UptimeCompaction{}, errNothingToCompact
+	}
+	first, err := ns.db.findFirstUptimeTime()
+	if err == errCannotLoadDataFromDatabase || (err == nil && !first.Before(boundary)) {
+		return UptimeCompaction{}, errNothingToCompact
+	}
+	if err != nil {
+		return UptimeCompaction{}, errCannotLoadDataFromDatabase
+	}
+
+	closedMonths, err := ns.db.findClosedMonths()
+	if err != nil {
+		return UptimeCompaction{}, errCannotLoadDataFromDatabase
+	}
+	closed := make(map[YearMonth]bool)
+	for _, closedMonth := range closedMonths {
+		closed[YearMonth{Year: closedMonth.Year, Month: time.Month(closedMonth.Month)}] = true
+	}
+	for month := monthOf(first, location); month.before(oldest); month = month.next() {
+		if !closed[month] {
+			log.Warnf("Month %v is not closed, raw uptimes before %v are not compacted", month, boundary)
+			return UptimeCompaction{}, errMonthsNotClosed
+		}
+		// hourly buckets are UTC hours, which would cross the month boundary
+		if hourly && !hourAligned(month.end(location), location) {
+			log.Warnf("Month %v does not end on a whole UTC hour in %v, raw uptimes are not compacted", month, location)
+			return UptimeCompaction{}, errCompactionUnaligned
+		}
+	}
+
+	from := previous
+	if from.IsZero() {
+		from = first
+	}
+	if err := ns.rebuildRollups(from, boundary); err != nil {
+		return UptimeCompaction{}, err
+	}
+
+	compaction := UptimeCompaction{CompactedBefore: boundary}
+	write := func(uptime Uptime) error { return nil }
+	var archive *uptimeArchive
+	if dir := viper.GetString("retention.archive-dir"); dir != "" {
+		if archive, err = createUptimeArchive(dir, from, boundary); err != nil {
+			return UptimeCompaction{}, err
+		}
+		write = archive.write
+	}
+	ids, err := ns.db.findCompactableUptimes(boundary, write)
+	if err != nil {
+		if archive != nil {
+			archive.discard()
+		}
+		return UptimeCompaction{}, err
+	}
+	if archive != nil {
+		if len(ids) == 0 {
+			archive.discard()
+		} else if err := archive.close(); err != nil {
+			return UptimeCompaction{}, err
+		} else {
+			compaction.ArchivePath = archive.path
+		}
+	}
+
+	compaction.RowCount = int64(len(ids))
+	if err := ns.db.compactUptimes(ids, &compaction); err != nil {
+		if compaction.ArchivePath != "" {
+			os.Remove(compaction.ArchivePath)
+		}
+		return UptimeCompaction{}, err
+	}
+	if compaction.ArchivePath != "" {
+		if err := markUptimeArchive(compaction.ArchivePath); err != nil {
+			log.Errorf("Unable to mark archive %v as complete - %v", compaction.ArchivePath, err)
+		}
+	}
+	log.Infof("Compacted %v raw uptimes which ended before %v", compaction.RowCount, boundary)
+	return compaction, nil
+}
+
+// CompactUptimes compacts raw uptimes according to the retention configuration, zero compaction is returned
+// when there is nothing to compact
+func CompactUptimes() (UptimeCompaction, error) {
+	ns := DefaultService()
+	compaction, err := ns.compactUptimes(time.Now())
+	if err == errNothingToCompact {
+		return UptimeCompaction{}, nil
+	}
+	return compaction, err
+}
+
+// archivedUptime is a single removed uptime row, written as one JSON line of the archive
+type archivedUptime struct {
+	Id        uint      `json:"id"`
+	NodeId    string    `json:"nodeId"`
+	StartTime int       `json:"startTime"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// uptimeArchive is a compressed file of removed uptimes, written under a temporary name until it is closed. Archives
+// are complete only when the marker written after the uptimes were removed from the database exists.
+type uptimeArchive struct {
+	path    string
+	tmpPath string
+	file    *os.File
+	writer  *gzip.Writer
+	encoder *json.Encoder
+}
+
+func createUptimeArchive(dir string, from time.Time, boundary time.Time) (*uptimeArchive, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("uptimes_%s_%s%s", from.UTC().Format("20060102T150405"), boundary.UTC().Format("20060102T150405"), uptimeArchiveExtension)
+	archive := &uptimeArchive{
+		path:    filepath.Join(dir, name),
+		tmpPath: filepath.Join(dir, "."+name),
+	}
+	file, err := os.Create(archive.tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	archive.file = file
+	archive.writer = gzip.NewWriter(file)
+	archive.encoder = json.NewEncoder(archive.writer)
+	return archive, nil
+}
+
+func (a *uptimeArchive) write(uptime Uptime) error {
+	return a.encoder.Encode(archivedUptime{
+		Id:        uptime.Id,
+		NodeId:    uptime.NodeId,
+		StartTime: uptime.StartTime,
+		CreatedAt: uptime.CreatedAt,
+		UpdatedAt: uptime.UpdatedAt,
+	})
+}
+
+// close flushes the archive to the disk and moves it under its final name
+func (a *uptimeArchive) close() error {
+	if err := a.writer.Close(); err != nil {
+		a.discard()
+		return err
+	}
+	if err := a.file.Sync(); err != nil {
+		a.discard()
+		return err
+	}
+	if err := a.file.Close(); err != nil {
+		os.Remove(a.tmpPath)
+		return err
+	}
+	return os.Rename(a.tmpPath, a.path)
+}
+
+func (a *uptimeArchive) discard() {
+	a.file.Close()
+	os.Remove(a.tmpPath)
+}
+
+// markUptimeArchive creates the marker of the archive once its uptimes were removed from the database
+func markUptimeArchive(path string) error {
+	marker, err := os.Create(path + uptimeArchiveMarker)
+	if err != nil {
+		return err
+	}
+	if err := marker.Sync(); err != nil {
+		marker.Close()
+		return err
+	}
+	return marker.Close()
+}
@@ -1,16 +1,17 @@
 package node_checker
 
 import (
-	"encoding/csv"
+	"bytes"
+	"crypto/subtle"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
 	"strconv"
 
 	"github.com/SkycoinPro/skywire-services-uptime/src/api"
+	"github.com/SkycoinPro/skywire-services-uptime/src/database/postgres"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
@@ -19,19 +20,44 @@ import (
 const Nodes = "nodes"
 const StartDate = "startDate"
 const EndDate = "endDate"
+const Page = "page"
+const PageSize = "pageSize"
+const Period = "period"
+const Granularity = "granularity"
+const Timezone = "timezone"
+const Sort = "sort"
+const Order = "order"
+const Resolution = "resolution"
+
+// AdminKey is the context key of the name of the admin authenticated by the request token
+const AdminKey = "admin"
+
+const defaultPageSize = 50
+const maxPageSize = 500
+
+// leadership tells whether this instance is the one collecting data
+type leadership interface {
+	IsLeader() bool
+}
 
 // Controller is handling requests regarding Model
 type Controller struct {
 	nodeService Service
+	leader      leadership
 }
 
 func DefaultController() Controller {
-	return NewController(DefaultService())
+	// exports are signed on request, an invalid key would otherwise be found only by the first export
+	if _, _, err := signingKey(); err != nil {
+		log.Fatalf("Unable to use signing.secret-key - %v", err)
+	}
+	return NewController(DefaultService(), postgres.Elector)
 }
 
-func NewController(ns Service) Controller {
+func NewController(ns Service, leader leadership) Controller {
 	return Controller{
 		nodeService: ns,
+		leader:      leader,
 	}
 }
 
@@ -42,6 +68,94 @@ func (ctrl Controller) RegisterAPIs(public *gin.RouterGroup, closed *gin.RouterG
 	publicUserGroup.GET("/getNodeInfo", ctrl.getNodeInfo)
 	publicUserGroup.GET("/getNodeInfoExport", ctrl.getPreviousMonthInfo)
 	publicUserGroup.GET("/getAllUptimes", ctrl.getAllUptimes)
+	publicUserGroup.GET("/sources", ctrl.getSourcesHealth)
+	publicUserGroup.GET("/gaps", ctrl.getCollectorGaps)
+	publicUserGroup.GET("/signingKey", ctrl.getSigningKey)
+
+	publicUptimesGroup := public.Group("/uptimes")
+	publicUptimesGroup.GET("/series", ctrl.getUptimeSeries)
+
+	publicRunsGroup := public.Group("/runs")
+	publicRunsGroup.GET("", ctrl.getCollectionRuns)
+	publicRunsGroup.GET("/:id", ctrl.getCollectionRun)
+
+	publicNodesGroup := public.Group("/nodes")
+	publicNodesGroup.GET("/:key/metadata", ctrl.getNodeMetadata)
+	publicNodesGroup.GET("/:key/events", ctrl.getNodeEvents)
+	publicNodesGroup.GET("/:key/reliability", ctrl.getNodeReliability)
+	publicNodesGroup.GET("/:key/adjustments", ctrl.getNodeAdjustments)
+
+	publicOwnersGroup := public.Group("/owners")
+	publicOwnersGroup.GET("", ctrl.getOwners)
+	publicOwnersGroup.GET("/:id", ctrl.getOwner)
+	publicOwnersGroup.GET("/:id/uptime", ctrl.getOwnerUptime)
+
+	publicGroupsGroup := public.Group("/groups")
+	publicGroupsGroup.GET("", ctrl.getGroups)
+	publicGroupsGroup.GET("/:id", ctrl.getGroup)
+	publicGroupsGroup.GET("/:id/uptime", ctrl.getGroupUptime)
+
+	publicMaintenanceGroup := public.Group("/maintenance")
+	publicMaintenanceGroup.GET("", ctrl.getMaintenanceWindows)
+	publicMaintenanceGroup.GET("/:id", ctrl.getMaintenanceWindow)
+
+	publicChainGroup := public.Group("/chain")
+	publicChainGroup.GET("", ctrl.getReportChain)
+
+	publicReportsGroup := public.Group("/reports")
+	publicReportsGroup.GET("/:period", ctrl.getMonthlyReport)
+	publicReportsGroup.GET("/:period/proof/:key", ctrl.getMerkleProof)
+
+	publicFleetGroup := public.Group("/fleet")
+	publicFleetGroup.GET("/series", ctrl.getFleetSeries)
+
+	publicReliabilityGroup := public.Group("/reliability")
+	publicReliabilityGroup.GET("", ctrl.getFleetReliability)
+
+	publicEligibilityGroup := public.Group("/eligibility")
+	publicEligibilityGroup.GET("", ctrl.getEligibilityPolicies)
+	publicEligibilityGroup.GET("/:policy", ctrl.evaluateEligibility)
+
+	closedChainGroup := closed.Group("/chain", ctrl.authenticateAdmin)
+	closedChainGroup.GET("/verify", ctrl.verifyReportChain)
+
+	closedRollupGroup := closed.Group("/rollups", ctrl.authenticateAdmin)
+	closedRollupGroup.POST("/monthly", ctrl.recomputeMonthlyUptimes)
+	closedRollupGroup.POST("/series", ctrl.rebuildUptimeRollups)
+
+	closedPayoutGroup := closed.Group("/payouts", ctrl.authenticateAdmin)
+	closedPayoutGroup.POST("", ctrl.createPayout)
+	closedPayoutGroup.GET("", ctrl.getPayoutRuns)
+	closedPayoutGroup.GET("/:id", ctrl.getPayoutRun)
+	closedPayoutGroup.GET("/:id/export", ctrl.exportPayoutRun)
+
+	closedOwnersGroup := closed.Group("/owners", ctrl.authenticateAdmin)
+	closedOwnersGroup.POST("", ctrl.createOwner)
+	closedOwnersGroup.PUT("/:id", ctrl.updateOwner)
+	closedOwnersGroup.DELETE("/:id", ctrl.deleteOwner)
+	closedOwnersGroup.POST("/:id/nodes", ctrl.addOwnerNodes)
+	closedOwnersGroup.DELETE("/:id/nodes/:key", ctrl.removeOwnerNode)
+
+	closedGroupsGroup := closed.Group("/groups", ctrl.authenticateAdmin)
+	closedGroupsGroup.POST("", ctrl.createGroup)
+	closedGroupsGroup.PUT("/:id", ctrl.updateGroup)
+	closedGroupsGroup.DELETE("/:id", ctrl.deleteGroup)
+	closedGroupsGroup.POST("/:id/nodes", ctrl.addGroupNodes)
+	closedGroupsGroup.DELETE("/:id/nodes/:key", ctrl.removeGroupNode)
+
+	closedMaintenanceGroup := closed.Group("/maintenance", ctrl.authenticateAdmin)
+	closedMaintenanceGroup.POST("", ctrl.createMaintenanceWindow)
+	closedMaintenanceGroup.PUT("/:id", ctrl.updateMaintenanceWindow)
+	closedMaintenanceGroup.DELETE("/:id", ctrl.deleteMaintenanceWindow)
+
+	closedAdjustmentsGroup := closed.Group("/adjustments", ctrl.authenticateAdmin)
+	closedAdjustmentsGroup.GET("", ctrl.getAdjustments)
+	closedAdjustmentsGroup.POST("", ctrl.createAdjustment)
+	closedAdjustmentsGroup.GET("/:id", ctrl.getAdjustment)
+	closedAdjustmentsGroup.POST("/:id/revert", ctrl.revertAdjustment)
+
+	closedHeartbeatGroup := closed.Group("/heartbeats")
+	closedHeartbeatGroup.POST("", ctrl.receiveHeartbeat)
 }
 
 func (ctrl Controller) getAllUptimes(c *gin.Context) {
@@ -58,21 +172,27 @@ func (ctrl Controller) getAllUptimes(c *gin.Context) {
 			endDate = time.Unix(end, 0)
 		}
 	}
-	response, err := ctrl.nodeService.exportAllNodesUptimes(startDate, endDate)
+	location, err := timezone(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+	response, err := ctrl.nodeService.exportAllNodesUptimes(startDate, endDate, location)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
 		return
 	}
-	c.JSON(200, response)
+	signedJSON(c, response)
 
 }
 
 // @Summary Returns uptime info for previous month
-// @Description Returns uptime info for nodes from the request
+// @Description Returns uptime info for nodes from the request. When signing is configured, the signature of the body is returned in X-Signature headers.
 // @Tags nodes
 // @Accept json
 // @Produce json
 // @Param nodes query string true "Node for checking of uptime status"
+// @Param timezone query string false "IANA timezone in which the previous month is cut, defaults to the configured one"
 // @Success 200 {array} node_checker.NodeUptimeResponse
 // @Failure 400 {object} api.ErrorResponse
 // @Failure 500 {object} api.ErrorResponse
@@ -99,15 +219,21 @@ func (ctrl Controller) getPreviousMonthInfo(c *gin.Context) {
 		}
 	}
 
+	location, err := timezone(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+
 	nodesString := params[Nodes][0]
 	//TODO consider returning some warning that node all keys were matched
-	detail, err := ctrl.nodeService.getNodeInfoExport(strings.Split(nodesString, ","), startDate, endDate)
+	detail, err := ctrl.nodeService.getNodeInfoExport(strings.Split(nodesString, ","), startDate, endDate, location)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	c.JSON(200, detail)
+	signedJSON(c, detail)
 }
 
 // @Summary Returns uptime info
@@ -116,6 +242,7 @@ func (ctrl Controller) getPreviousMonthInfo(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param nodes query string true "Node for checking of uptime status"
+// @Param timezone query string false "IANA timezone in which the current month is cut, defaults to the configured one"
 // @Success 200 {array} node_checker.NodeUptimeResponse
 // @Failure 400 {object} api.ErrorResponse
 // @Failure 500 {object} api.ErrorResponse
@@ -127,9 +254,14 @@ func (ctrl Controller) getNodeInfo(c *gin.Context) {
 		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: "uptime service: zero nodes in request"})
 		return
 	}
+	location, err := timezone(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: err.Error()})
+		return
+	}
 	nodesString := params[Nodes][0]
 	//TODO consider returning some warning that node all keys were matched
-	detail, err := ctrl.nodeService.getNodeInfo(strings.Split(nodesString, ","))
+	detail, err := ctrl.nodeService.getNodeInfo(strings.Split(nodesString, ","), location)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
 		return
@@ -142,331 +274,1505 @@ func (ctrl Controller) getNodeInfo(c *gin.Context) {
 // @Tags nodes
 // @Accept json
 // @Produce json
-// @Success 200
+// @Success 200 {object} node_checker.CollectionRun
+// @Failure 409 {object} api.ErrorResponse
 // @Failure 500 {object} api.ErrorResponse
 // @Router /info/updateNodeInfo [get]
 func (ctrl Controller) updateNodeInfo(c *gin.Context) {
-	err := ctrl.nodeService.updateNodeInfo()
+	if !ctrl.leader.IsLeader() {
+		c.AbortWithStatusJSON(http.StatusConflict, api.ErrorResponse{Error: errNotLeader.Error()})
+		return
+	}
+	run, err := ctrl.nodeService.updateNodeInfo()
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
 		return
 	}
+	c.JSON(200, run)
 }
 
-func (ctrl Controller) testFuncForMonthlyUptimes() error {
-	sumForAllNodesUptimes := []int{}
-	var totalStartTime int
-	var totalMonthlyStartTimeForNode int
-	sumForAllNodesUptimesAfterFuncCall := []int{}
-	nodesThatAreNotSame := []string{}
-	allNodes, err := ctrl.nodeService.getNodes()
+// @Summary Returns collection runs
+// @Description Returns reports of collection runs, newest first
+// @Tags runs
+// @Produce json
+// @Param page query int false "Page number, starting from 1"
+// @Param pageSize query int false "Number of runs per page"
+// @Success 200 {object} node_checker.CollectionRunsPage
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /runs [get]
+func (ctrl Controller) getCollectionRuns(c *gin.Context) {
+	page, pageSize, err := pagination(c)
 	if err != nil {
-		return err
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: err.Error()})
+		return
 	}
-	for _, node := range allNodes {
-		nodeWithUptimes, erro := ctrl.nodeService.getNode(node.Key)
-		if erro != nil {
-			log.Error("Cannot find node with %v key ", node.Key)
-		}
-		totalStartTime = 0
-		for _, uptime := range nodeWithUptimes.Uptimes {
-			totalStartTime += uptime.StartTime
-		}
-		sumForAllNodesUptimes = append(sumForAllNodesUptimes, totalStartTime)
+	runs, err := ctrl.nodeService.getCollectionRuns(page, pageSize)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
 	}
-	ctrl.GetUptimesForPreviousMonths()
+	c.JSON(200, runs)
+}
 
-	for _, node := range allNodes {
-		nodeWithUptimes, erro := ctrl.nodeService.getNode(node.Key)
-		if erro != nil {
-			log.Error("Cannot find node with %v key ", node.Key)
-		}
-		totalStartTime = 0
-		for _, uptime := range nodeWithUptimes.Uptimes {
-			totalStartTime += uptime.StartTime
-		}
-		sumForAllNodesUptimesAfterFuncCall = append(sumForAllNodesUptimesAfterFuncCall, totalStartTime)
+// @Summary Returns collection run
+// @Description Returns report of a single collection run
+// @Tags runs
+// @Produce json
+// @Param id path int true "Collection run id"
+// @Success 200 {object} node_checker.CollectionRun
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /runs/{id} [get]
+func (ctrl Controller) getCollectionRun(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: errUnableToProcessRequest.Error()})
+		return
 	}
+	run, err := ctrl.nodeService.getCollectionRun(uint(id))
+	if err == errCannotLoadDataFromDatabase {
+		c.AbortWithStatusJSON(http.StatusNotFound, api.ErrorResponse{Error: errCannotFindCollectionRun.Error()})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, run)
+}
 
-	if len(sumForAllNodesUptimes) == len(sumForAllNodesUptimesAfterFuncCall) {
-		for i, j := 0, 0; i < len(sumForAllNodesUptimes); i++ {
-			if sumForAllNodesUptimes[i] != sumForAllNodesUptimesAfterFuncCall[j] {
-				nodesThatAreNotSame = append(nodesThatAreNotSame, "wrong")
-			}
-			j++
-		}
-	} else {
-		log.Error("Number of nodes is different after function call")
+// @Summary Returns node metadata
+// @Description Returns current metadata of the node together with history of every attribute change
+// @Tags nodes
+// @Produce json
+// @Param key path string true "Node key"
+// @Success 200 {object} node_checker.NodeMetadataResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /nodes/{key}/metadata [get]
+func (ctrl Controller) getNodeMetadata(c *gin.Context) {
+	metadata, err := ctrl.nodeService.getNodeMetadata(c.Param("key"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
 	}
+	c.JSON(200, metadata)
+}
 
-	file, err := os.Create("startTimeDifference.csv")
+// @Summary Returns node events
+// @Description Returns state transitions of the node within the range together with restart counts per period
+// @Tags nodes
+// @Produce json
+// @Param key path string true "Node key"
+// @Param startDate query int false "Unix timestamp of range start"
+// @Param endDate query int false "Unix timestamp of range end"
+// @Param period query string false "Granularity of restart counts, day or month"
+// @Param timezone query string false "IANA timezone in which days and months are cut, defaults to the configured one"
+// @Success 200 {object} node_checker.NodeEventsResponse
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /nodes/{key}/events [get]
+func (ctrl Controller) getNodeEvents(c *gin.Context) {
+	startDate, endDate := dateRange(c)
+	location, err := timezone(c)
 	if err != nil {
-		log.Debug("Cannot create csv file")
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: err.Error()})
+		return
 	}
-	defer file.Close()
-	writer := csv.NewWriter(file)
-	var headline = []string{"node_key", "difference"}
-	err = writer.Write(headline)
+	events, err := ctrl.nodeService.getNodeEvents(c.Param("key"), startDate, endDate, c.Query(Period), location)
 	if err != nil {
-		log.Debug("Cannot write csv file")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
 	}
+	c.JSON(200, events)
+}
 
-	allDifferences := []UptimeAndMonthlyUptimeDifference{}
-	sortedAllDifferences := []UptimeAndMonthlyUptimeDifference{}
-	for _, node := range allNodes {
-		nodeWithMonthlyUptimes, erro := ctrl.nodeService.getNode(node.Key)
-		if erro != nil {
-			log.Error("Cannot find node with %v key ", node.Key)
-		}
-		totalStartTime = 0
-		totalMonthlyStartTimeForNode = 0
-		for _, uptime := range nodeWithMonthlyUptimes.Uptimes {
-			totalStartTime += uptime.StartTime
-		}
-		for _, monthlyUptime := range nodeWithMonthlyUptimes.MonthlyUptimes {
-			totalMonthlyStartTimeForNode += monthlyUptime.TotalStartTime
-		}
-		if totalStartTime != totalMonthlyStartTimeForNode {
-			log.Error("Sum of all uptimes for node and all monthly uptimes are not same")
-			difference := totalStartTime - totalMonthlyStartTimeForNode
-			uptimeAndMonthlyUptimeDifference := UptimeAndMonthlyUptimeDifference{
-				NodeId:     node.Key,
-				Difference: difference,
-			}
-			allDifferences = append(allDifferences, uptimeAndMonthlyUptimeDifference)
-		}
+// @Summary Returns node reliability
+// @Description Returns MTBF, MTTR, streaks, restarts and availability of the node within the range, durations are in seconds
+// @Tags nodes
+// @Produce json
+// @Param key path string true "Node key"
+// @Param startDate query int false "Unix timestamp of range start"
+// @Param endDate query int false "Unix timestamp of range end"
+// @Success 200 {object} node_checker.NodeReliability
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /nodes/{key}/reliability [get]
+func (ctrl Controller) getNodeReliability(c *gin.Context) {
+	startDate, endDate := dateRange(c)
+	reliability, err := ctrl.nodeService.getNodeReliability(c.Param("key"), startDate, endDate)
+	if err == errCannotLoadDataFromDatabase {
+		c.AbortWithStatusJSON(http.StatusNotFound, api.ErrorResponse{Error: errCannotFindNodeWithKey.Error()})
+		return
 	}
-	for 0 < len(allDifferences) {
-		min := 0
-		for j := 1; j < len(allDifferences); j++ {
-			if allDifferences[j].Difference > allDifferences[min].Difference {
-				min = j
-			}
-		}
-		sortedAllDifferences = append(sortedAllDifferences, allDifferences[min])
-		allDifferences = append(allDifferences[:min], allDifferences[min+1:]...)
-	}
-	for _, sortedDifference := range sortedAllDifferences {
-		differenceString := strconv.Itoa(sortedDifference.Difference)
-		values := []string{sortedDifference.NodeId, differenceString}
-		err = writer.Write(values)
-		if err != nil {
-			log.Debug("Cannot insert values into csv file")
-		}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
 	}
-	return nil
+	c.JSON(200, reliability)
 }
 
-func (ctrl Controller) GetUptimesForPreviousMonths() {
-
-	nodes, err := ctrl.nodeService.getNodes()
+// @Summary Returns fleet reliability
+// @Description Returns reliability of every node within the range as a sortable table
+// @Tags nodes
+// @Produce json
+// @Param startDate query int false "Unix timestamp of range start"
+// @Param endDate query int false "Unix timestamp of range end"
+// @Param sort query string false "key, availability, mtbf, mttr, longestStreak, currentStreak, restarts or restartRate, defaults to availability"
+// @Param order query string false "asc or desc, defaults to desc"
+// @Param page query int false "Page number, starting from 1"
+// @Param pageSize query int false "Number of nodes per page"
+// @Success 200 {object} node_checker.FleetReliabilityPage
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /reliability [get]
+func (ctrl Controller) getFleetReliability(c *gin.Context) {
+	page, pageSize, err := pagination(c)
 	if err != nil {
-		log.Fatal("Cannot get any node")
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: err.Error()})
+		return
 	}
-
-	var startDate, endDate time.Time
-	currentLocation := endDate.Location()
-
-	for _, node := range nodes {
-		nodeKeys := []string{node.Key}
-		for i := 2; i <= 12; i++ {
-			convertStartMonth := time.Month(i)
-			startDate = time.Date(2019, convertStartMonth, 1, 0, 0, 0, 0, currentLocation)
-
-			convertEndMonth := time.Month(i + 1)
-			endDate = time.Date(2019, convertEndMonth, 1, 0, 0, 0, 0, currentLocation)
-			details, err := ctrl.nodeService.getNodeInfoExport(nodeKeys, startDate, endDate)
-			if err != nil {
-				log.Error("Cannot get node info")
-			}
-			for _, detail := range details {
-				if detail.Uptime != 0 {
-					uptimeInt := int(detail.Uptime)
-					downtimeInt := int(detail.Downtime)
-					err := ctrl.nodeService.createUptimesForPastMonths(detail.Key, i, 2019, uptimeInt, detail.Percentage, downtimeInt)
-					if err != nil {
-						log.Error("Cannot make new monthly uptime record", err)
-					}
-				}
-			}
-		}
+	startDate, endDate := dateRange(c)
+	descending := c.DefaultQuery(Order, "desc") != "asc"
+	fleet, err := ctrl.nodeService.getFleetReliability(startDate, endDate, c.DefaultQuery(Sort, ReliabilitySortAvailability), descending, page, pageSize)
+	if err == errUnknownSortField {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
 	}
+	c.JSON(200, fleet)
 }
 
-func (ctrl Controller) RunningRoutine() {
-	diff := viper.GetDuration("server.refresh-interval")
-	jobTicker := &jobTicker{}
-	//ctrl.nodeService.updateNodeInfo()
-	//ctrl.compareCSV()
-	//ctrl.compareCsvWithMU()
-	//ctrl.getUptimesForPreviousMonths()
-	//ctrl.testFuncForMonthlyUptimes()
-	jobTicker.updateTimer(diff)
-	for {
-		<-jobTicker.timer.C
-		log.Info("Scheduler triggered, current time: ", time.Now())
-		//ctrl.nodeService.updateNodeInfo()
-		jobTicker.updateTimer(diff)
+// @Summary Returns fleet time series
+// @Description Returns numbers of known, online, offline, new, restarted and newly offline nodes recorded by collection runs, downsampled to min, max and average per bucket
+// @Tags nodes
+// @Produce json
+// @Param startDate query int false "Unix timestamp of range start, last 7 days when not set"
+// @Param endDate query int false "Unix timestamp of range end, last 7 days when not set"
+// @Param resolution query string false "Bucket size as duration, e.g. 5m, 1h or 24h, defaults to 1h"
+// @Success 200 {array} node_checker.FleetSeriesPoint
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /fleet/series [get]
+func (ctrl Controller) getFleetSeries(c *gin.Context) {
+	startDate, endDate := dateRange(c)
+	if _, found := c.GetQuery(StartDate); !found {
+		startDate = endDate.Add(-7 * 24 * time.Hour)
+	}
+	resolution, err := time.ParseDuration(c.DefaultQuery(Resolution, "1h"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: errInvalidResolution.Error()})
+		return
 	}
+	series, err := ctrl.nodeService.getFleetSeries(startDate, endDate, resolution)
+	if err == errInvalidResolution || err == errInvalidSeriesRange {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, series)
 }
 
-type exportDate struct {
-	StartDate int64
-	EndDate   int64
+// @Summary Recomputes monthly uptimes
+// @Description Recomputes and stores monthly uptimes for every month in the inclusive range, months are cut in the configured reporting timezone
+// @Tags rollups
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param range body node_checker.MonthRangeRequest true "Months in YYYY-MM format"
+// @Success 200 {array} string
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /rollups/monthly [post]
+func (ctrl Controller) recomputeMonthlyUptimes(c *gin.Context) {
+	var request MonthRangeRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: errInvalidMonth.Error()})
+		return
+	}
+	from, err1 := parseYearMonth(request.From)
+	to, err2 := parseYearMonth(request.To)
+	if err1 != nil || err2 != nil || to.before(from) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: errInvalidMonth.Error()})
+		return
+	}
+	months, err := ctrl.nodeService.rollupMonths(from, to)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+	var recomputed []string
+	for _, month := range months {
+		recomputed = append(recomputed, month.String())
+	}
+	c.JSON(200, recomputed)
 }
 
-func (ctrl Controller) compareCSV() error {
-
-	var sliceOfConvertedCsvFiles1 []CSVDifferences
-	var sliceOfConvertedCsvFiles2 []CSVDifferences
-	var csvFileDifferences []CSVDifferences
-	f, err := os.Open("C:/Users/Nesa/go-workspace/src/github.com/SkycoinPro/skywire-services-uptime/minerExport_1_12_2019-1_1_2020_all (1).csv")
-	defer f.Close()
+// @Summary Returns uptime time series
+// @Description Returns uptime seconds and percentage of every node per hour or day bucket within the range. Days in a timezone other than the configured reporting one are summed from hourly rollups, so they require hourly rollups and a timezone offset from UTC by whole hours.
+// @Tags nodes
+// @Produce json
+// @Param nodes query string true "Comma separated node keys"
+// @Param startDate query int true "Unix timestamp of range start"
+// @Param endDate query int true "Unix timestamp of range end"
+// @Param granularity query string false "hour or day, defaults to day"
+// @Param timezone query string false "IANA timezone in which days are cut, defaults to the configured one"
+// @Success 200 {array} node_checker.NodeUptimeSeries
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /uptimes/series [get]
+func (ctrl Controller) getUptimeSeries(c *gin.Context) {
+	params := c.Request.URL.Query()
+	if len(params[Nodes]) <= 0 || len(params[StartDate]) <= 0 || len(params[EndDate]) <= 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: "uptime service: nodes and range are required"})
+		return
+	}
+	startDate, endDate := dateRange(c)
+	location, err := timezone(c)
 	if err != nil {
-		return err
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+	granularity := c.DefaultQuery(Granularity, GranularityDay)
+	series, err := ctrl.nodeService.getUptimeSeries(strings.Split(params[Nodes][0], ","), startDate, endDate, granularity, location)
+	if err == errInvalidGranularity || err == errInvalidSeriesRange || err == errSeriesTimezoneUnavailable || err == errSeriesTimezoneUnaligned {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: err.Error()})
+		return
 	}
-	lines, err := csv.NewReader(f).ReadAll()
 	if err != nil {
-		return err
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
 	}
-	header := true
-	for _, line := range lines {
-		if header {
-			header = false
-			continue
-		}
-		s, _ := strconv.ParseFloat(line[5], 64)
-		csvDiff1 := CSVDifferences{
-			NodeID: line[2],
-			Uptime: s,
-		}
-		sliceOfConvertedCsvFiles1 = append(sliceOfConvertedCsvFiles1, csvDiff1)
-		fmt.Println(csvDiff1)
+	c.JSON(200, series)
+}
 
+// @Summary Rebuilds uptime rollups
+// @Description Recomputes hourly and daily uptime rollups of every node within the range from raw uptimes
+// @Tags rollups
+// @Produce json
+// @Security AdminToken
+// @Param startDate query int true "Unix timestamp of range start"
+// @Param endDate query int true "Unix timestamp of range end"
+// @Success 200
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /rollups/series [post]
+func (ctrl Controller) rebuildUptimeRollups(c *gin.Context) {
+	startDate, endDate := dateRange(c)
+	if err := ctrl.nodeService.rebuildRollups(startDate, endDate); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
 	}
+	c.Status(200)
+}
+
+// @Summary Returns eligibility policies
+// @Description Returns reward eligibility policies defined in the configuration
+// @Tags eligibility
+// @Produce json
+// @Success 200 {array} node_checker.EligibilityPolicy
+// @Router /eligibility [get]
+func (ctrl Controller) getEligibilityPolicies(c *gin.Context) {
+	c.JSON(200, eligibilityPolicies())
+}
 
-	file, err := os.Open("C:/Users/Nesa/go-workspace/src/github.com/SkycoinPro/skywire-services-uptime/minerExport_1_12_2019-1_1_2020_all.csv")
-	defer file.Close()
+// @Summary Evaluates eligibility policy
+// @Description Evaluates the policy against every node and returns eligible and ineligible nodes with reasons of each failure
+// @Tags eligibility
+// @Produce json
+// @Param policy path string true "Policy name"
+// @Param startDate query int false "Unix timestamp of period start, previous month when not set"
+// @Param endDate query int false "Unix timestamp of period end, previous month when not set"
+// @Param timezone query string false "IANA timezone in which days and the default month are cut, defaults to the configured one"
+// @Success 200 {object} node_checker.EligibilityReport
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /eligibility/{policy} [get]
+func (ctrl Controller) evaluateEligibility(c *gin.Context) {
+	policy, err := findEligibilityPolicy(c.Param("policy"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+	location, err := timezone(c)
 	if err != nil {
-		return err
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+	previousMonth := monthOf(time.Now(), location).previous()
+	startDate, endDate := previousMonth.start(location), previousMonth.end(location)
+	if _, found := c.GetQuery(StartDate); found {
+		startDate, endDate = dateRange(c)
 	}
-	lines2, err := csv.NewReader(file).ReadAll()
+	report, err := ctrl.nodeService.evaluateEligibility(policy, startDate, endDate, location)
 	if err != nil {
-		return err
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
 	}
-	header2 := true
-	for _, line := range lines2 {
-		if header2 {
-			header2 = false
-			continue
-		}
-		s, _ := strconv.ParseFloat(line[5], 64)
-		csvDiff2 := CSVDifferences{
-			NodeID: line[2],
-			Uptime: s,
-		}
-		sliceOfConvertedCsvFiles2 = append(sliceOfConvertedCsvFiles2, csvDiff2)
-		fmt.Println(csvDiff2)
+	c.JSON(200, report)
+}
+
+// @Summary Calculates payout
+// @Description Distributes the budget among nodes eligible by the policy within the month and stores the payout run with the authenticated admin who created it
+// @Tags payouts
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param payout body node_checker.PayoutRequest true "Budget, policy, scheme and period"
+// @Success 200 {object} node_checker.PayoutReport
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /payouts [post]
+func (ctrl Controller) createPayout(c *gin.Context) {
+	var request PayoutRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: errUnableToProcessRequest.Error()})
+		return
+	}
+	run, err := ctrl.nodeService.calculatePayout(request, c.GetString(AdminKey))
+	switch err {
+	case nil:
+		c.JSON(200, PayoutReport{Run: run, Addresses: payoutAddresses(run)})
+	case errInvalidBudget, errUnknownPayoutScheme, errNoPayoutTiers, errNothingToPay, errUnknownEligibilityPolicy, errInvalidMonth, errInvalidTimezone:
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
 	}
+}
 
-	newFile, err := os.Create("csvDifference.csv")
+// @Summary Returns payout runs
+// @Description Returns stored payout runs without entries, newest first
+// @Tags payouts
+// @Produce json
+// @Security AdminToken
+// @Param page query int false "Page number, starting from 1"
+// @Param pageSize query int false "Number of runs per page"
+// @Success 200 {object} node_checker.PayoutRunsPage
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /payouts [get]
+func (ctrl Controller) getPayoutRuns(c *gin.Context) {
+	page, pageSize, err := pagination(c)
 	if err != nil {
-		log.Debug("Cannot create csv file")
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: err.Error()})
+		return
 	}
-	defer newFile.Close()
-	writer := csv.NewWriter(newFile)
-	var headline = []string{"node_key", "difference"}
-	err = writer.Write(headline)
+	runs, err := ctrl.nodeService.getPayoutRuns(page, pageSize)
 	if err != nil {
-		log.Debug("Cannot write csv file")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
 	}
+	c.JSON(200, runs)
+}
 
-	for i := 0; i <= len(sliceOfConvertedCsvFiles1)-1; i++ {
-		diff := sliceOfConvertedCsvFiles1[i].Uptime - sliceOfConvertedCsvFiles2[i].Uptime
-		if sliceOfConvertedCsvFiles1[i].NodeID != sliceOfConvertedCsvFiles2[i].NodeID {
-			log.Info("node key from first file %v and node key from second file %v are not same", sliceOfConvertedCsvFiles1[i].NodeID, sliceOfConvertedCsvFiles2[i].NodeID)
-		}
-		if diff == 0 {
-			continue
-		}
-		csvFileDiff := CSVDifferences{
-			NodeID: sliceOfConvertedCsvFiles1[i].NodeID,
-			Uptime: diff,
-		}
-		csvFileDifferences = append(csvFileDifferences, csvFileDiff)
+// @Summary Returns payout run
+// @Description Returns stored payout run with amounts per node and per owner address
+// @Tags payouts
+// @Produce json
+// @Security AdminToken
+// @Param id path int true "Payout run id"
+// @Success 200 {object} node_checker.PayoutReport
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /payouts/{id} [get]
+func (ctrl Controller) getPayoutRun(c *gin.Context) {
+	run, ok := ctrl.payoutRun(c)
+	if !ok {
+		return
 	}
+	c.JSON(200, PayoutReport{Run: run, Addresses: payoutAddresses(run)})
+}
 
-	for _, csvFileDifference := range csvFileDifferences {
-		diffFloatToString := fmt.Sprintf("%f", csvFileDifference.Uptime)
-		values := []string{csvFileDifference.NodeID, diffFloatToString}
-		err = writer.Write(values)
-		if err != nil {
-			log.Debug("Cannot insert values into csv file")
-		}
+// @Summary Exports payout run
+// @Description Exports stored payout run per node as csv or json, or per owner address as skycoin CLI batch file
+// @Tags payouts
+// @Produce plain
+// @Security AdminToken
+// @Param id path int true "Payout run id"
+// @Param format query string false "csv, json or skycoin, defaults to csv"
+// @Success 200 {string} string
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /payouts/{id}/export [get]
+func (ctrl Controller) exportPayoutRun(c *gin.Context) {
+	run, ok := ctrl.payoutRun(c)
+	if !ok {
+		return
 	}
-
-	return nil
+	var buffer bytes.Buffer
+	format := c.DefaultQuery("format", ExportFormatCSV)
+	if err := WritePayout(&buffer, run, format); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+	contentType, extension := "text/csv", "csv"
+	if format == ExportFormatJSON {
+		contentType, extension = "application/json", "json"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=payout-%d-%s.%s", run.Id, format, extension))
+	c.Data(200, contentType, buffer.Bytes())
 }
 
-func (ctrl Controller) compareCsvWithMU() error {
-
-	mus := []CSVDifferences{}
-	sliceOfConvertedCsvFiles2 := []CSVDifferences{}
+// payoutRun loads the payout run from the id path parameter, aborting the request when it fails
+func (ctrl Controller) payoutRun(c *gin.Context) (PayoutRun, bool) {
+	id, ok := pathId(c)
+	if !ok {
+		return PayoutRun{}, false
+	}
+	run, err := ctrl.nodeService.getPayoutRun(id)
+	if err == errCannotLoadDataFromDatabase {
+		c.AbortWithStatusJSON(http.StatusNotFound, api.ErrorResponse{Error: errCannotFindPayoutRun.Error()})
+		return PayoutRun{}, false
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return PayoutRun{}, false
+	}
+	return run, true
+}
 
-	allNodes, err := ctrl.nodeService.getNodes()
+// @Summary Returns owners
+// @Description Returns all owners with keys of their nodes
+// @Tags owners
+// @Produce json
+// @Success 200 {array} node_checker.Owner
+// @Failure 500 {object} api.ErrorResponse
+// @Router /owners [get]
+func (ctrl Controller) getOwners(c *gin.Context) {
+	owners, err := ctrl.nodeService.getOwners()
 	if err != nil {
-		return err
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
 	}
-	for _, node := range allNodes {
-		nodeWithUptimes, erro := ctrl.nodeService.getNode(node.Key)
-		if erro != nil {
-			log.Error("Cannot find node with %v key ", node.Key)
-		}
-		for _, nodeMu := range nodeWithUptimes.MonthlyUptimes {
-			if nodeMu.Month == 12 {
-				compare := CSVDifferences{
-					NodeID: nodeMu.NodeId,
-					Uptime: float64(nodeMu.TotalStartTime),
-				}
-				mus = append(mus, compare)
-			}
-		}
+	c.JSON(200, owners)
+}
+
+// @Summary Returns owner
+// @Description Returns the owner with keys of its nodes
+// @Tags owners
+// @Produce json
+// @Param id path int true "Owner id"
+// @Success 200 {object} node_checker.Owner
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /owners/{id} [get]
+func (ctrl Controller) getOwner(c *gin.Context) {
+	id, ok := pathId(c)
+	if !ok {
+		return
 	}
-	file, err := os.Open("C:/Users/Nesa/go-workspace/src/github.com/SkycoinPro/skywire-services-uptime/minerExport_1_12_2019-1_1_2020_all (2).csv")
-	defer file.Close()
+	owner, err := ctrl.nodeService.getOwner(id)
 	if err != nil {
-		return err
+		abortWithMembershipError(c, err, errCannotFindOwner)
+		return
 	}
-	lines2, err := csv.NewReader(file).ReadAll()
-	if err != nil {
-		return err
+	c.JSON(200, owner)
+}
+
+// @Summary Returns owner uptime
+// @Description Returns summed uptime of owner nodes within the range together with contribution of every node
+// @Tags owners
+// @Produce json
+// @Param id path int true "Owner id"
+// @Param startDate query int false "Unix timestamp of range start, current month when not set"
+// @Param endDate query int false "Unix timestamp of range end, current month when not set"
+// @Param timezone query string false "IANA timezone in which the current month is cut, defaults to the configured one"
+// @Success 200 {object} node_checker.AggregateUptimeReport
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /owners/{id}/uptime [get]
+func (ctrl Controller) getOwnerUptime(c *gin.Context) {
+	id, ok := pathId(c)
+	if !ok {
+		return
 	}
-	header2 := true
-	for _, line := range lines2 {
-		if header2 {
-			header2 = false
-			continue
-		}
-		i, _ := strconv.ParseFloat(line[5], 64)
-		if i > 0 {
-			csvDiff2 := CSVDifferences{
-				NodeID: line[2],
-				Uptime: i,
-			}
-			sliceOfConvertedCsvFiles2 = append(sliceOfConvertedCsvFiles2, csvDiff2)
-			fmt.Println(csvDiff2)
-		}
+	startDate, endDate, ok := reportRange(c)
+	if !ok {
+		return
 	}
-	for i := 0; i <= len(sliceOfConvertedCsvFiles2)-1; i++ {
-		//diff := mus[i].Uptime - sliceOfConvertedCsvFiles2[i].Uptime
-		if mus[i].NodeID != sliceOfConvertedCsvFiles2[i].NodeID {
-			log.Info("node key from first file %v and node key from second file %v are not same", mus[i].NodeID, sliceOfConvertedCsvFiles2[i].NodeID)
-		}
+	report, err := ctrl.nodeService.getOwnerUptime(id, startDate, endDate)
+	if err != nil {
+		abortWithMembershipError(c, err, errCannotFindOwner)
+		return
 	}
-	return nil
+	c.JSON(200, report)
 }
 
-type CSVDifferences struct {
-	NodeID string
-	Uptime float64
+// @Summary Creates owner
+// @Description Creates an owner with an optional skycoin address which receives payouts for its nodes
+// @Tags owners
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param owner body node_checker.OwnerRequest true "Owner"
+// @Success 201 {object} node_checker.Owner
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /owners [post]
+func (ctrl Controller) createOwner(c *gin.Context) {
+	var request OwnerRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: errUnableToProcessRequest.Error()})
+		return
+	}
+	owner, err := ctrl.nodeService.createOwner(request, c.GetString(AdminKey))
+	if err != nil {
+		abortWithMembershipError(c, err, errCannotFindOwner)
+		return
+	}
+	c.JSON(http.StatusCreated, owner)
+}
+
+// @Summary Updates owner
+// @Description Updates name and address of the owner
+// @Tags owners
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param id path int true "Owner id"
+// @Param owner body node_checker.OwnerRequest true "Owner"
+// @Success 200 {object} node_checker.Owner
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /owners/{id} [put]
+func (ctrl Controller) updateOwner(c *gin.Context) {
+	id, ok := pathId(c)
+	if !ok {
+		return
+	}
+	var request OwnerRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: errUnableToProcessRequest.Error()})
+		return
+	}
+	owner, err := ctrl.nodeService.updateOwner(id, request, c.GetString(AdminKey))
+	if err != nil {
+		abortWithMembershipError(c, err, errCannotFindOwner)
+		return
+	}
+	c.JSON(200, owner)
+}
+
+// @Summary Deletes owner
+// @Description Deletes the owner, its nodes are left without owner and its groups without owner
+// @Tags owners
+// @Security AdminToken
+// @Param id path int true "Owner id"
+// @Success 204
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /owners/{id} [delete]
+func (ctrl Controller) deleteOwner(c *gin.Context) {
+	id, ok := pathId(c)
+	if !ok {
+		return
+	}
+	if err := ctrl.nodeService.deleteOwner(id, c.GetString(AdminKey)); err != nil {
+		abortWithMembershipError(c, err, errCannotFindOwner)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Assigns nodes to owner
+// @Description Assigns nodes to the owner, nodes owned by another owner are moved
+// @Tags owners
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param id path int true "Owner id"
+// @Param nodes body node_checker.NodeKeysRequest true "Node keys"
+// @Success 200 {object} node_checker.Owner
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /owners/{id}/nodes [post]
+func (ctrl Controller) addOwnerNodes(c *gin.Context) {
+	id, ok := pathId(c)
+	if !ok {
+		return
+	}
+	var request NodeKeysRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: errUnableToProcessRequest.Error()})
+		return
+	}
+	owner, err := ctrl.nodeService.addOwnerNodes(id, request.Nodes, c.GetString(AdminKey))
+	if err != nil {
+		abortWithMembershipError(c, err, errCannotFindOwner)
+		return
+	}
+	c.JSON(200, owner)
+}
+
+// @Summary Removes node from owner
+// @Tags owners
+// @Security AdminToken
+// @Param id path int true "Owner id"
+// @Param key path string true "Node key"
+// @Success 204
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /owners/{id}/nodes/{key} [delete]
+func (ctrl Controller) removeOwnerNode(c *gin.Context) {
+	id, ok := pathId(c)
+	if !ok {
+		return
+	}
+	if err := ctrl.nodeService.removeOwnerNode(id, c.Param("key"), c.GetString(AdminKey)); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Returns node groups
+// @Description Returns all node groups with keys of their nodes
+// @Tags groups
+// @Produce json
+// @Success 200 {array} node_checker.NodeGroup
+// @Failure 500 {object} api.ErrorResponse
+// @Router /groups [get]
+func (ctrl Controller) getGroups(c *gin.Context) {
+	groups, err := ctrl.nodeService.getGroups()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, groups)
+}
+
+// @Summary Returns node group
+// @Description Returns the node group with keys of its nodes
+// @Tags groups
+// @Produce json
+// @Param id path int true "Group id"
+// @Success 200 {object} node_checker.NodeGroup
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /groups/{id} [get]
+func (ctrl Controller) getGroup(c *gin.Context) {
+	id, ok := pathId(c)
+	if !ok {
+		return
+	}
+	group, err := ctrl.nodeService.getGroup(id)
+	if err != nil {
+		abortWithMembershipError(c, err, errCannotFindGroup)
+		return
+	}
+	c.JSON(200, group)
+}
+
+// @Summary Returns node group uptime
+// @Description Returns summed uptime of group nodes within the range together with contribution of every node
+// @Tags groups
+// @Produce json
+// @Param id path int true "Group id"
+// @Param startDate query int false "Unix timestamp of range start, current month when not set"
+// @Param endDate query int false "Unix timestamp of range end, current month when not set"
+// @Param timezone query string false "IANA timezone in which the current month is cut, defaults to the configured one"
+// @Success 200 {object} node_checker.AggregateUptimeReport
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /groups/{id}/uptime [get]
+func (ctrl Controller) getGroupUptime(c *gin.Context) {
+	id, ok := pathId(c)
+	if !ok {
+		return
+	}
+	startDate, endDate, ok := reportRange(c)
+	if !ok {
+		return
+	}
+	report, err := ctrl.nodeService.getGroupUptime(id, startDate, endDate)
+	if err != nil {
+		abortWithMembershipError(c, err, errCannotFindGroup)
+		return
+	}
+	c.JSON(200, report)
+}
+
+// @Summary Creates node group
+// @Description Creates a node group, optionally belonging to an owner
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param group body node_checker.GroupRequest true "Group"
+// @Success 201 {object} node_checker.NodeGroup
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /groups [post]
+func (ctrl Controller) createGroup(c *gin.Context) {
+	var request GroupRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: errUnableToProcessRequest.Error()})
+		return
+	}
+	group, err := ctrl.nodeService.createGroup(request, c.GetString(AdminKey))
+	if err != nil {
+		abortWithMembershipError(c, err, errCannotFindOwner)
+		return
+	}
+	c.JSON(http.StatusCreated, group)
+}
+
+// @Summary Updates node group
+// @Description Updates name and owner of the node group
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param id path int true "Group id"
+// @Param group body node_checker.GroupRequest true "Group"
+// @Success 200 {object} node_checker.NodeGroup
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /groups/{id} [put]
+func (ctrl Controller) updateGroup(c *gin.Context) {
+	id, ok := pathId(c)
+	if !ok {
+		return
+	}
+	var request GroupRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: errUnableToProcessRequest.Error()})
+		return
+	}
+	group, err := ctrl.nodeService.updateGroup(id, request, c.GetString(AdminKey))
+	if err != nil {
+		abortWithMembershipError(c, err, errCannotFindGroup)
+		return
+	}
+	c.JSON(200, group)
+}
+
+// @Summary Deletes node group
+// @Tags groups
+// @Security AdminToken
+// @Param id path int true "Group id"
+// @Success 204
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /groups/{id} [delete]
+func (ctrl Controller) deleteGroup(c *gin.Context) {
+	id, ok := pathId(c)
+	if !ok {
+		return
+	}
+	if err := ctrl.nodeService.deleteGroup(id, c.GetString(AdminKey)); err != nil {
+		abortWithMembershipError(c, err, errCannotFindGroup)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Adds nodes to node group
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param id path int true "Group id"
+// @Param nodes body node_checker.NodeKeysRequest true "Node keys"
+// @Success 200 {object} node_checker.NodeGroup
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /groups/{id}/nodes [post]
+func (ctrl Controller) addGroupNodes(c *gin.Context) {
+	id, ok := pathId(c)
+	if !ok {
+		return
+	}
+	var request NodeKeysRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: errUnableToProcessRequest.Error()})
+		return
+	}
+	group, err := ctrl.nodeService.addGroupNodes(id, request.Nodes, c.GetString(AdminKey))
+	if err != nil {
+		abortWithMembershipError(c, err, errCannotFindGroup)
+		return
+	}
+	c.JSON(200, group)
+}
+
+// @Summary Removes node from node group
+// @Tags groups
+// @Security AdminToken
+// @Param id path int true "Group id"
+// @Param key path string true "Node key"
+// @Success 204
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /groups/{id}/nodes/{key} [delete]
+func (ctrl Controller) removeGroupNode(c *gin.Context) {
+	id, ok := pathId(c)
+	if !ok {
+		return
+	}
+	if err := ctrl.nodeService.removeGroupNode(id, c.Param("key"), c.GetString(AdminKey)); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// abortWithMembershipError maps errors of owner and group operations to response statuses
+func abortWithMembershipError(c *gin.Context, err error, notFound error) {
+	switch err {
+	case errCannotLoadDataFromDatabase:
+		c.AbortWithStatusJSON(http.StatusNotFound, api.ErrorResponse{Error: notFound.Error()})
+	case errInvalidName, errInvalidAddress, errUnableToProcessRequest:
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: err.Error()})
+	case errNameAlreadyUsed:
+		c.AbortWithStatusJSON(http.StatusConflict, api.ErrorResponse{Error: err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+	}
+}
+
+// @Summary Returns maintenance windows
+// @Description Returns maintenance windows overlapping the period, all of them when the period is not set
+// @Tags maintenance
+// @Produce json
+// @Param startDate query int false "Unix timestamp of period start"
+// @Param endDate query int false "Unix timestamp of period end"
+// @Param node query string false "Node key, only global windows and windows of the node are returned"
+// @Success 200 {array} node_checker.MaintenanceWindow
+// @Failure 500 {object} api.ErrorResponse
+// @Router /maintenance [get]
+func (ctrl Controller) getMaintenanceWindows(c *gin.Context) {
+	startDate, endDate := dateRange(c)
+	if _, found := c.GetQuery(EndDate); !found {
+		endDate = time.Unix(1<<40, 0)
+	}
+	windows, err := ctrl.nodeService.getMaintenanceWindows(startDate, endDate, c.Query("node"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, windows)
+}
+
+// @Summary Returns maintenance window
+// @Tags maintenance
+// @Produce json
+// @Param id path int true "Maintenance window id"
+// @Success 200 {object} node_checker.MaintenanceWindow
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /maintenance/{id} [get]
+func (ctrl Controller) getMaintenanceWindow(c *gin.Context) {
+	id, ok := pathId(c)
+	if !ok {
+		return
+	}
+	window, err := ctrl.nodeService.getMaintenanceWindow(id)
+	if err != nil {
+		abortWithMaintenanceError(c, err)
+		return
+	}
+	c.JSON(200, window)
+}
+
+// @Summary Declares maintenance window
+// @Description Declares a global maintenance window or a window of a single node, its time is excused under the configured maintenance policy, the authenticated admin is recorded with it
+// @Tags maintenance
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param window body node_checker.MaintenanceWindowRequest true "Maintenance window"
+// @Success 201 {object} node_checker.MaintenanceWindow
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /maintenance [post]
+func (ctrl Controller) createMaintenanceWindow(c *gin.Context) {
+	var request MaintenanceWindowRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: errUnableToProcessRequest.Error()})
+		return
+	}
+	window, err := ctrl.nodeService.createMaintenanceWindow(request, c.GetString(AdminKey))
+	if err != nil {
+		abortWithMaintenanceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, window)
+}
+
+// @Summary Updates maintenance window
+// @Tags maintenance
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param id path int true "Maintenance window id"
+// @Param window body node_checker.MaintenanceWindowRequest true "Maintenance window"
+// @Success 200 {object} node_checker.MaintenanceWindow
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /maintenance/{id} [put]
+func (ctrl Controller) updateMaintenanceWindow(c *gin.Context) {
+	id, ok := pathId(c)
+	if !ok {
+		return
+	}
+	var request MaintenanceWindowRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: errUnableToProcessRequest.Error()})
+		return
+	}
+	window, err := ctrl.nodeService.updateMaintenanceWindow(id, request, c.GetString(AdminKey))
+	if err != nil {
+		abortWithMaintenanceError(c, err)
+		return
+	}
+	c.JSON(200, window)
+}
+
+// @Summary Deletes maintenance window
+// @Tags maintenance
+// @Security AdminToken
+// @Param id path int true "Maintenance window id"
+// @Success 204
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /maintenance/{id} [delete]
+func (ctrl Controller) deleteMaintenanceWindow(c *gin.Context) {
+	id, ok := pathId(c)
+	if !ok {
+		return
+	}
+	if err := ctrl.nodeService.deleteMaintenanceWindow(id, c.GetString(AdminKey)); err != nil {
+		abortWithMaintenanceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// abortWithMaintenanceError maps errors of maintenance window operations to response statuses
+func abortWithMaintenanceError(c *gin.Context, err error) {
+	switch err {
+	case errCannotLoadDataFromDatabase:
+		c.AbortWithStatusJSON(http.StatusNotFound, api.ErrorResponse{Error: errCannotFindMaintenanceWindow.Error()})
+	case errInvalidMaintenanceWindow:
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+	}
+}
+
+// @Summary Returns node adjustments
+// @Description Returns manual uptime adjustments of the node overlapping the period including reverted ones, all of them when the period is not set
+// @Tags nodes
+// @Produce json
+// @Param key path string true "Node key"
+// @Param startDate query int false "Unix timestamp of period start"
+// @Param endDate query int false "Unix timestamp of period end"
+// @Success 200 {array} node_checker.UptimeAdjustment
+// @Failure 500 {object} api.ErrorResponse
+// @Router /nodes/{key}/adjustments [get]
+func (ctrl Controller) getNodeAdjustments(c *gin.Context) {
+	ctrl.listAdjustments(c, c.Param("key"))
+}
+
+// @Summary Returns adjustments
+// @Description Returns manual uptime adjustments overlapping the period including reverted ones, all of them when the period is not set
+// @Tags adjustments
+// @Produce json
+// @Security AdminToken
+// @Param startDate query int false "Unix timestamp of period start"
+// @Param endDate query int false "Unix timestamp of period end"
+// @Param node query string false "Node key"
+// @Success 200 {array} node_checker.UptimeAdjustment
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /adjustments [get]
+func (ctrl Controller) getAdjustments(c *gin.Context) {
+	ctrl.listAdjustments(c, c.Query("node"))
+}
+
+func (ctrl Controller) listAdjustments(c *gin.Context, nodeKey string) {
+	startDate, endDate := dateRange(c)
+	if _, found := c.GetQuery(EndDate); !found {
+		endDate = time.Unix(1<<40, 0)
+	}
+	adjustments, err := ctrl.nodeService.getAdjustments(startDate, endDate, nodeKey)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, adjustments)
+}
+
+// @Summary Returns adjustment
+// @Tags adjustments
+// @Produce json
+// @Security AdminToken
+// @Param id path int true "Adjustment id"
+// @Success 200 {object} node_checker.UptimeAdjustment
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /adjustments/{id} [get]
+func (ctrl Controller) getAdjustment(c *gin.Context) {
+	id, ok := pathId(c)
+	if !ok {
+		return
+	}
+	adjustment, err := ctrl.nodeService.getAdjustment(id)
+	if err != nil {
+		abortWithAdjustmentError(c, err)
+		return
+	}
+	c.JSON(200, adjustment)
+}
+
+// @Summary Creates adjustment
+// @Description Credits or debits uptime seconds of the node spread over the period, the authenticated admin is recorded as its author
+// @Tags adjustments
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param adjustment body node_checker.AdjustmentRequest true "Adjustment"
+// @Success 201 {object} node_checker.UptimeAdjustment
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /adjustments [post]
+func (ctrl Controller) createAdjustment(c *gin.Context) {
+	var request AdjustmentRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: errUnableToProcessRequest.Error()})
+		return
+	}
+	adjustment, err := ctrl.nodeService.createAdjustment(request, c.GetString(AdminKey))
+	if err != nil {
+		abortWithAdjustmentError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, adjustment)
+}
+
+// @Summary Reverts adjustment
+// @Description Stops applying the adjustment, it is kept with the authenticated admin who reverted it and the reason
+// @Tags adjustments
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param id path int true "Adjustment id"
+// @Param revert body node_checker.RevertAdjustmentRequest true "Reason"
+// @Success 200 {object} node_checker.UptimeAdjustment
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /adjustments/{id}/revert [post]
+func (ctrl Controller) revertAdjustment(c *gin.Context) {
+	id, ok := pathId(c)
+	if !ok {
+		return
+	}
+	var request RevertAdjustmentRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: errUnableToProcessRequest.Error()})
+		return
+	}
+	adjustment, err := ctrl.nodeService.revertAdjustment(id, request, c.GetString(AdminKey))
+	if err != nil {
+		abortWithAdjustmentError(c, err)
+		return
+	}
+	c.JSON(200, adjustment)
+}
+
+// abortWithAdjustmentError maps errors of adjustment operations to response statuses
+func abortWithAdjustmentError(c *gin.Context, err error) {
+	switch err {
+	case errCannotLoadDataFromDatabase:
+		c.AbortWithStatusJSON(http.StatusNotFound, api.ErrorResponse{Error: errCannotFindAdjustment.Error()})
+	case errInvalidAdjustment:
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: err.Error()})
+	case errAdjustmentAlreadyReverted:
+		c.AbortWithStatusJSON(http.StatusConflict, api.ErrorResponse{Error: err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+	}
+}
+
+// authenticateAdmin aborts requests without the bearer token of a configured admin and stores the admin name in the context
+func (ctrl Controller) authenticateAdmin(c *gin.Context) {
+	header := c.GetHeader("Authorization")
+	token := strings.TrimPrefix(header, "Bearer ")
+	if token != "" && token != header {
+		for _, admin := range adminTokens() {
+			if admin.Token != "" && subtle.ConstantTimeCompare([]byte(admin.Token), []byte(token)) == 1 {
+				c.Set(AdminKey, admin.Name)
+				c.Next()
+				return
+			}
+		}
+	}
+	c.AbortWithStatusJSON(http.StatusUnauthorized, api.ErrorResponse{Error: errUnauthorized.Error()})
+}
+
+// @Summary Returns report chain
+// @Description Returns published monthly reports in the order of publication with their hashes
+// @Tags reports
+// @Produce json
+// @Success 200 {array} node_checker.MonthlyReport
+// @Failure 500 {object} api.ErrorResponse
+// @Router /chain [get]
+func (ctrl Controller) getReportChain(c *gin.Context) {
+	reports, err := ctrl.nodeService.getReportChain()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, reports)
+}
+
+// @Summary Verifies report chain
+// @Description Recomputes hashes and links of published monthly reports and compares the latest revision of every month with stored monthly uptimes
+// @Tags reports
+// @Produce json
+// @Security AdminToken
+// @Param head query string false "Previously seen chain head which the chain has to contain"
+// @Success 200 {object} node_checker.ChainVerification
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /chain/verify [get]
+func (ctrl Controller) verifyReportChain(c *gin.Context) {
+	verification, err := ctrl.nodeService.verifyReportChain(c.Query("head"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, verification)
+}
+
+// @Summary Returns monthly report
+// @Description Returns the latest revision of the published report of the month with its canonical content
+// @Tags reports
+// @Produce json
+// @Param period path string true "Month in YYYY-MM format"
+// @Success 200 {object} node_checker.MonthlyReportResponse
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /reports/{period} [get]
+func (ctrl Controller) getMonthlyReport(c *gin.Context) {
+	report, err := ctrl.nodeService.getMonthlyReport(c.Param("period"))
+	switch err {
+	case nil:
+		c.JSON(200, report)
+	case errInvalidMonth:
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: err.Error()})
+	case errCannotLoadDataFromDatabase:
+		c.AbortWithStatusJSON(http.StatusNotFound, api.ErrorResponse{Error: errCannotFindMonthlyReport.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+	}
+}
+
+// @Summary Returns Merkle inclusion proof
+// @Description Returns proof that the node leaf (key, uptime, percentage) is part of the Merkle root of the latest revision of the published report of the month
+// @Tags reports
+// @Produce json
+// @Param period path string true "Month in YYYY-MM format"
+// @Param key path string true "Node key"
+// @Success 200 {object} node_checker.MerkleProof
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /reports/{period}/proof/{key} [get]
+func (ctrl Controller) getMerkleProof(c *gin.Context) {
+	proof, err := ctrl.nodeService.getMerkleProof(c.Param("period"), c.Param("key"))
+	switch err {
+	case nil:
+		c.JSON(200, proof)
+	case errInvalidMonth:
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: err.Error()})
+	case errCannotLoadDataFromDatabase:
+		c.AbortWithStatusJSON(http.StatusNotFound, api.ErrorResponse{Error: errCannotFindMonthlyReport.Error()})
+	case errCannotFindNodeInReport:
+		c.AbortWithStatusJSON(http.StatusNotFound, api.ErrorResponse{Error: err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+	}
+}
+
+type MonthRangeRequest struct {
+	From string `json:"from" binding:"required"`
+	To   string `json:"to" binding:"required"`
+}
+
+// dateRange reads startDate and endDate query parameters, defaulting to the whole history
+func dateRange(c *gin.Context) (time.Time, time.Time) {
+	startDate := time.Unix(0, 0)
+	endDate := time.Now()
+	params := c.Request.URL.Query()
+	if len(params[StartDate]) > 0 && len(params[EndDate]) > 0 {
+		start, err1 := strconv.ParseInt(params[StartDate][0], 10, 64)
+		end, err2 := strconv.ParseInt(params[EndDate][0], 10, 64)
+		if err1 == nil && err2 == nil {
+			startDate = time.Unix(start, 0)
+			endDate = time.Unix(end, 0)
+		}
+	}
+	return startDate, endDate
+}
+
+// timezone reads the timezone query parameter, defaulting to the configured reporting timezone
+func timezone(c *gin.Context) (*time.Location, error) {
+	name, found := c.GetQuery(Timezone)
+	if !found {
+		return reportingLocation(), nil
+	}
+	return loadLocation(name)
+}
+
+// signedJSON responds with the export encoded as compact JSON and, when signing is configured,
+// with the detached signature of the body in response headers
+func signedJSON(c *gin.Context, export interface{}) {
+	content, err := canonicalExport(export)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+	signature, err := signExport(content)
+	if err != nil {
+		log.Error("Unable to sign export - ", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if signature != nil {
+		c.Header(SignatureHeader, signature.Signature)
+		c.Header(SignaturePublicKeyHeader, signature.PublicKey)
+		c.Header(SignatureHashHeader, signature.Hash)
+	}
+	c.Data(200, "application/json; charset=utf-8", content)
+}
+
+// pathId reads the id path parameter, aborting the request when it is not a number
+func pathId(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: errUnableToProcessRequest.Error()})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// reportRange reads startDate and endDate query parameters, defaulting to the current month in the requested timezone
+func reportRange(c *gin.Context) (time.Time, time.Time, bool) {
+	location, err := timezone(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: err.Error()})
+		return time.Time{}, time.Time{}, false
+	}
+	now := time.Now()
+	startDate, endDate := monthOf(now, location).start(location), now
+	if _, found := c.GetQuery(StartDate); found {
+		startDate, endDate = dateRange(c)
+	}
+	return startDate, endDate, true
+}
+
+// pagination reads page and page size query parameters
+func pagination(c *gin.Context) (int, int, error) {
+	page, pageSize := 1, defaultPageSize
+	params := c.Request.URL.Query()
+	if len(params[Page]) > 0 {
+		value, err := strconv.Atoi(params[Page][0])
+		if err != nil || value < 1 {
+			return 0, 0, errInvalidPagination
+		}
+		page = value
+	}
+	if len(params[PageSize]) > 0 {
+		value, err := strconv.Atoi(params[PageSize][0])
+		if err != nil || value < 1 || value > maxPageSize {
+			return 0, 0, errInvalidPagination
+		}
+		pageSize = value
+	}
+	return page, pageSize, nil
+}
+
+// @Summary Returns node sources health
+// @Description Returns health status of every configured discovery source
+// @Tags nodes
+// @Produce json
+// @Success 200 {array} node_checker.SourceHealth
+// @Router /info/sources [get]
+func (ctrl Controller) getSourcesHealth(c *gin.Context) {
+	c.JSON(200, ctrl.nodeService.getSourcesHealth())
+}
+
+// @Summary Returns signing key
+// @Description Returns the public key and skycoin address of the key which signs uptime exports
+// @Tags nodes
+// @Produce json
+// @Success 200 {object} node_checker.SigningKeyResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /info/signingKey [get]
+func (ctrl Controller) getSigningKey(c *gin.Context) {
+	key, err := ctrl.nodeService.getSigningKey()
+	switch err {
+	case nil:
+		c.JSON(200, key)
+	case errSigningNotConfigured:
+		c.AbortWithStatusJSON(http.StatusNotFound, api.ErrorResponse{Error: err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+	}
+}
+
+// @Summary Returns collector gaps
+// @Description Returns periods in which the collector was not observing nodes
+// @Tags nodes
+// @Produce json
+// @Param startDate query int false "Unix timestamp of period start"
+// @Param endDate query int false "Unix timestamp of period end"
+// @Success 200 {array} node_checker.CollectorGap
+// @Failure 500 {object} api.ErrorResponse
+// @Router /info/gaps [get]
+func (ctrl Controller) getCollectorGaps(c *gin.Context) {
+	startDate, endDate := dateRange(c)
+	gaps, err := ctrl.nodeService.getCollectorGaps(startDate, endDate)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(200, gaps)
+}
+
+// @Summary Receives heartbeat from a visor
+// @Description Records uptime pushed by a visor, heartbeat has to be signed with the node secret key
+// @Tags nodes
+// @Accept json
+// @Produce json
+// @Param heartbeat body node_checker.HeartbeatRequest true "Signed heartbeat"
+// @Success 202
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Failure 429 {object} api.ErrorResponse
+// @Failure 500 {object} api.ErrorResponse
+// @Router /heartbeats [post]
+func (ctrl Controller) receiveHeartbeat(c *gin.Context) {
+	var heartbeat HeartbeatRequest
+	if err := c.BindJSON(&heartbeat); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: errInvalidHeartbeat.Error()})
+		return
+	}
+	err := ctrl.nodeService.receiveHeartbeat(heartbeat)
+	switch err {
+	case nil:
+		c.Status(http.StatusAccepted)
+	case errInvalidHeartbeat, errStaleHeartbeat:
+		c.AbortWithStatusJSON(http.StatusBadRequest, api.ErrorResponse{Error: err.Error()})
+	case errInvalidHeartbeatSignature:
+		c.AbortWithStatusJSON(http.StatusUnauthorized, api.ErrorResponse{Error: err.Error()})
+	case errReplayedHeartbeat:
+		c.AbortWithStatusJSON(http.StatusConflict, api.ErrorResponse{Error: err.Error()})
+	case errHeartbeatRateLimited:
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, api.ErrorResponse{Error: err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+	}
+}
+
+// RollupRoutine closes months which ended and were not rolled up yet and compacts raw uptimes of months
+// out of retention. The check runs shortly after startup, right after every month boundary and periodically
+// in between, so that a new leader catches up.
+func (ctrl Controller) RollupRoutine() {
+	checkInterval := viper.GetDuration("rollup.check-interval")
+	if checkInterval <= 0 {
+		checkInterval = time.Hour
+	}
+	jobTicker := &jobTicker{}
+	jobTicker.updateTimer(time.Minute)
+	for {
+		<-jobTicker.timer.C
+		if ctrl.leader.IsLeader() {
+			if err := ctrl.nodeService.fillMissingMonths(time.Now()); err != nil {
+				log.Error("Unable to roll up monthly uptimes ", err)
+			} else if retentionMonths() > 0 {
+				if _, err := ctrl.nodeService.compactUptimes(time.Now()); err != nil && err != errNothingToCompact {
+					log.Error("Unable to compact raw uptimes ", err)
+				}
+			}
+		}
+		diff := time.Until(nextRollupTime(time.Now(), reportingLocation()))
+		if diff > checkInterval {
+			diff = checkInterval
+		}
+		jobTicker.updateTimer(diff)
+	}
+}
+
+func (ctrl Controller) RunningRoutine() {
+	diff := viper.GetDuration("server.refresh-interval")
+	jobTicker := &jobTicker{}
+	jobTicker.updateTimer(diff)
+	for {
+		<-jobTicker.timer.C
+		log.Info("Scheduler triggered, current time: ", time.Now())
+		if ctrl.leader.IsLeader() {
+			ctrl.nodeService.updateNodeInfo()
+		} else {
+			log.Info("Skipping update, another instance is collecting data")
+		}
+		jobTicker.updateTimer(diff)
+	}
 }
@@ -3,9 +3,12 @@ package node_checker
 import (
 	"github.com/SkycoinPro/skywire-services-uptime/src/database/postgres"
 
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -13,15 +16,90 @@ import (
 type store interface {
 	findNodes() ([]Node, error)
 	findNode(key string) (Node, error)
-	createNode(node *Node) error
-	updateUptime(uptime *Uptime) error
-	createUptime(uptime *Uptime) error
-	updateNodeOnlineStatus(node *Node, status bool, currentTime time.Time) error
-	updateAllNodesOnlineStatus(currentTime time.Time) error
-	getLastUptimeForNode(nodeKey string) (Uptime, error)
-	createMonthlyUptime(monthlyUptime *MonthlyUptime) error
+	replaceMonthlyUptimes(year int, month int, monthlyUptimes []MonthlyUptime) error
+	findMonthlyUptimes(year int, month int) ([]MonthlyUptime, error)
+	findFirstUptimeTime() (time.Time, error)
+	findClosedMonths() ([]ClosedMonth, error)
+	saveClosedMonth(closedMonth *ClosedMonth) error
+	createMonthlyReport(report *MonthlyReport) error
+	findMonthlyReports(withContent bool) ([]MonthlyReport, error)
+	findMonthlyReport(year int, month int) (MonthlyReport, error)
+	findLastUptimes(nodeKeys []string) (map[string]Uptime, error)
+	findPeriodUptimes(startDate time.Time, endDate time.Time) (map[string][]Uptime, error)
+	findFirstUptimeTimes() (map[string]time.Time, error)
+	useHeartbeatNonce(nodeKey string, nonce string, sent time.Time, expiredBefore time.Time) error
+	saveCollection(batch *collectionBatch) error
+	createCollectionRun(run *CollectionRun) error
+	findCollectionRuns(page int, pageSize int) ([]CollectionRun, int, error)
+	updateCollectionRun(run *CollectionRun) error
+	findCollectionRun(id uint) (CollectionRun, error)
+	findOfflineNodes(nodeKeys []string) (map[string]bool, error)
+	findNodeEvents(nodeKey string, startDate time.Time, endDate time.Time) ([]NodeEvent, error)
+	countNodeEvents(eventType string, startDate time.Time, endDate time.Time) (map[string]int, error)
+	countUptimeRestarts(startDate time.Time, endDate time.Time) (map[string]int, error)
+	findCurrentMetadata(nodeKeys []string) (map[string]map[string]NodeMetadata, error)
+	findNodeMetadata(nodeKey string) ([]NodeMetadata, error)
+	findUptimeRollups(nodeKeys []string, granularity string, startDate time.Time, endDate time.Time) ([]UptimeRollup, error)
+	rebuildUptimeRollups(startDate time.Time, endDate time.Time, build func(uptimes map[string][]Uptime) []UptimeRollup) error
+	findLastCompaction() (UptimeCompaction, error)
+	findCompactableUptimes(boundary time.Time, each func(uptime Uptime) error) ([]uint, error)
+	compactUptimes(ids []uint, compaction *UptimeCompaction) error
+	findLastCheck() (time.Time, error)
+	copyMaintenanceAndAdjustments(schema string) error
+	findSchemaCollectionRuns(schema string) ([]CollectionRun, error)
+	saveCollectorGap(gap *CollectorGap) error
+	findCollectorGaps(startDate time.Time, endDate time.Time) ([]CollectorGap, error)
+	saveMaintenanceWindow(window *MaintenanceWindow) error
+	deleteMaintenanceWindow(id uint) error
+	findMaintenanceWindow(id uint) (MaintenanceWindow, error)
+	findMaintenanceWindows(startDate time.Time, endDate time.Time, nodeKey string) ([]MaintenanceWindow, error)
+	createAdjustment(adjustment *UptimeAdjustment) error
+	revertAdjustment(id uint, revertedBy string, reason string, revertedAt time.Time) error
+	findAdjustment(id uint) (UptimeAdjustment, error)
+	findAdjustments(startDate time.Time, endDate time.Time, nodeKey string, withReverted bool) ([]UptimeAdjustment, error)
+	findFleetSeries(startDate time.Time, endDate time.Time, resolution time.Duration) ([]fleetBucket, error)
+	saveOwner(owner *Owner) error
+	deleteOwner(id uint) error
+	findOwners() ([]Owner, error)
+	findOwner(id uint) (Owner, error)
+	addOwnerNodes(ownerId uint, nodeKeys []string, admin string) error
+	removeOwnerNode(ownerId uint, nodeKey string) error
+	findNodeOwners(nodeKeys []string) (map[string]Owner, error)
+	saveGroup(group *NodeGroup) error
+	deleteGroup(id uint) error
+	findGroups() ([]NodeGroup, error)
+	findGroup(id uint) (NodeGroup, error)
+	addGroupNodes(groupId uint, nodeKeys []string, admin string) error
+	removeGroupNode(groupId uint, nodeKey string) error
+	createPayoutRun(run *PayoutRun) error
+	findPayoutRuns(page int, pageSize int) ([]PayoutRun, int, error)
+	findPayoutRun(id uint) (PayoutRun, error)
 }
 
+// collectionBatch holds all changes produced by a single collection run, persisted in one transaction
+type collectionBatch struct {
+	RunId           *uint
+	CheckTime       time.Time
+	Heartbeat       bool              // nodes pushed a heartbeat, other nodes are not marked offline
+	LastUptimes     map[string]Uptime // last uptimes the batch was computed from
+	OnlineNodes     []string
+	NewUptimes      []Uptime
+	ExtendedUptimes []Uptime
+	ClosedMetadata  []uint
+	NewMetadata     []NodeMetadata
+	Events          []NodeEvent
+	Rollups         []UptimeRollup
+	NewNodes        int
+	Restarts        int
+	MarkedOffline   int64
+}
+
+// collectionLockId is the transaction level advisory lock taken while a collection run or a heartbeat is written
+const collectionLockId = 0x75707469
+
+// bulkChunkSize limits the number of rows per statement to stay below postgres bind parameters limit
+const bulkChunkSize = 1000
+
 // data implements store interface which uses GORM library
 type data struct {
 	db *gorm.DB
@@ -37,32 +115,56 @@ func NewData(database *gorm.DB) data {
 	}
 }
 
-func (u data) getLastUptimeForNode(nodeKey string) (Uptime, error) {
+func (u data) findNodes() ([]Node, error) {
+	var (
+		nodes   []Node
+		dbError error
+	)
+
+	record := u.db.Order("key ASC").Find(&nodes)
+	if record.RecordNotFound() {
+		return nil, errCannotLoadDataFromDatabase
+	}
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Error("Error occurred while fetching nodes - ", err)
+		}
+		return nil, dbError
+	}
+
+	return nodes, nil
+}
+
+func (u data) findNode(key string) (Node, error) {
 	var (
-		uptime  Uptime
+		node    Node
 		dbError error
 	)
-	record := u.db.Where("node_id = ?", nodeKey).Last(&uptime)
+	record := u.db.Where("key = ?", key).Preload("MonthlyUptimes", func(db *gorm.DB) *gorm.DB { return db.Order("Monthly_Uptimes.id ASC") }).Preload("Uptimes", func(db *gorm.DB) *gorm.DB { return db.Order("Uptimes.id ASC") }).Find(&node)
 	if record.RecordNotFound() {
-		return Uptime{}, errCannotLoadDataFromDatabase
+		return Node{}, errCannotLoadDataFromDatabase
 	}
 	if errs := record.GetErrors(); len(errs) > 0 {
 		for _, err := range errs {
 			dbError = err
-			log.Errorf("Error occurred while fetching uptime by key %v - %v", nodeKey, err)
+			log.Errorf("Error occurred while fetching node by key %v - %v", key, err)
 		}
-		return Uptime{}, dbError
+		return Node{}, dbError
 	}
 
-	return uptime, nil
+	return node, nil
 }
 
-func (u data) createUptime(uptime *Uptime) error {
+// replaceMonthlyUptimes replaces all monthly uptimes of the month in a single transaction, so rows of nodes which
+// are no longer part of the month do not survive a recompute
+func (u data) replaceMonthlyUptimes(year int, month int, monthlyUptimes []MonthlyUptime) error {
 	db := u.db.Begin()
-	var dbError error
-	for _, err := range db.Create(uptime).GetErrors() {
-		dbError = err
-		log.Error("Error while creating new uptime in DB %v", err)
+	now := time.Now()
+	dbError := execBulk(db, "removing monthly uptimes", "DELETE FROM monthly_uptimes WHERE year = ? AND month = ?", []interface{}{year, month})
+	for start := 0; start < len(monthlyUptimes) && dbError == nil; start += bulkChunkSize {
+		query, args := monthlyUptimesInsert(monthlyUptimes[start:minInt(start+bulkChunkSize, len(monthlyUptimes))], now)
+		dbError = execBulk(db, "creating monthly uptimes", query, args)
 	}
 	if dbError != nil {
 		db.Rollback()
@@ -73,41 +175,48 @@ func (u data) createUptime(uptime *Uptime) error {
 	return nil
 }
 
-func (u data) updateUptime(uptime *Uptime) error {
-	db := u.db
-	var dbError error
-	for _, err := range db.Model(&uptime).Update("StartTime", uptime.StartTime).GetErrors() {
-		dbError = err
-		log.Error("Error while updating uptime in DB ", err)
+func monthlyUptimesInsert(monthlyUptimes []MonthlyUptime, now time.Time) (string, []interface{}) {
+	values := make([]string, 0, len(monthlyUptimes))
+	args := make([]interface{}, 0, len(monthlyUptimes)*11)
+	for _, m := range monthlyUptimes {
+		values = append(values, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+		args = append(args, m.NodeId, m.Month, m.Year, m.TotalStartTime, m.Percentage, m.Downtime, m.Excused, m.Adjusted, m.LastStartTime, now, now)
 	}
-	if dbError != nil {
-		return dbError
-	}
-	return nil
+	query := "INSERT INTO monthly_uptimes (node_id, month, year, total_start_time, percentage, downtime, excused, adjusted, last_start_time, created_at, updated_at) VALUES " + strings.Join(values, ", ")
+	return query, args
 }
 
-func (u data) updateNodeOnlineStatus(node *Node, status bool, currentTime time.Time) error {
-	db := u.db.Begin()
-	var dbError error
-	for _, err := range db.Model(&node).UpdateColumns(Node{Online: status, LastCheck: currentTime, UpdatedAt: time.Now()}).GetErrors() {
-		dbError = err
-		log.Error("Error while updating node in DB ", err)
+func (u data) findLastCheck() (time.Time, error) {
+	var lastCheck pq.NullTime
+	if err := u.db.Model(&Node{}).Select("MAX(last_check)").Row().Scan(&lastCheck); err != nil {
+		log.Error("Error occurred while fetching last check time - ", err)
+		return time.Time{}, err
 	}
-	if dbError != nil {
-		db.Rollback()
-		return dbError
+	if !lastCheck.Valid {
+		return time.Time{}, errCannotLoadDataFromDatabase
 	}
-	db.Commit()
 
-	return nil
+	return lastCheck.Time, nil
 }
 
-func (u data) createNode(node *Node) error {
+// saveCollectorGap creates new gap or extends the existing one which started at the same time
+func (u data) saveCollectorGap(gap *CollectorGap) error {
 	db := u.db.Begin()
-	var dbError error
-	for _, err := range db.Create(node).GetErrors() {
+	var (
+		existing CollectorGap
+		dbError  error
+	)
+	record := db.Where("started_at = ?", gap.StartedAt).First(&existing)
+	if record.RecordNotFound() {
+		record = db.Create(gap)
+	} else if record.Error == nil && gap.EndedAt.After(existing.EndedAt) {
+		existing.EndedAt = gap.EndedAt
+		record = db.Model(&existing).UpdateColumns(CollectorGap{EndedAt: gap.EndedAt, UpdatedAt: time.Now()})
+		*gap = existing
+	}
+	for _, err := range record.GetErrors() {
 		dbError = err
-		log.Error("Error while creating new node in DB ", err)
+		log.Error("Error while saving collector gap in DB ", err)
 	}
 	if dbError != nil {
 		db.Rollback()
@@ -118,68 +227,449 @@ func (u data) createNode(node *Node) error {
 	return nil
 }
 
-func (u data) findNodes() ([]Node, error) {
+func (u data) findCollectorGaps(startDate time.Time, endDate time.Time) ([]CollectorGap, error) {
 	var (
-		nodes   []Node
+		gaps    []CollectorGap
 		dbError error
 	)
+	record := u.db.Where("started_at < ? AND ended_at > ?", endDate, startDate).Order("started_at ASC").Find(&gaps)
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Error("Error occurred while fetching collector gaps - ", err)
+		}
+		return nil, dbError
+	}
 
-	record := u.db.Order("key ASC").Find(&nodes)
+	return gaps, nil
+}
+
+func (u data) saveMaintenanceWindow(window *MaintenanceWindow) error {
+	return u.saveRecord(window, window.Id == 0, "maintenance window")
+}
+
+func (u data) deleteMaintenanceWindow(id uint) error {
+	return u.deleteRecord(&MaintenanceWindow{}, id, "maintenance window")
+}
+
+func (u data) findMaintenanceWindow(id uint) (MaintenanceWindow, error) {
+	var (
+		window  MaintenanceWindow
+		dbError error
+	)
+	record := u.db.Where("id = ?", id).First(&window)
 	if record.RecordNotFound() {
-		return nil, errCannotLoadDataFromDatabase
+		return MaintenanceWindow{}, errCannotLoadDataFromDatabase
 	}
 	if errs := record.GetErrors(); len(errs) > 0 {
 		for _, err := range errs {
 			dbError = err
-			log.Error("Error occurred while fetching nodes - ", err)
+			log.Errorf("Error occurred while fetching maintenance window %v - %v", id, err)
+		}
+		return MaintenanceWindow{}, dbError
+	}
+	return window, nil
+}
+
+// findMaintenanceWindows returns windows overlapping the period. When node key is set only global windows
+// and windows of that node are returned.
+func (u data) findMaintenanceWindows(startDate time.Time, endDate time.Time, nodeKey string) ([]MaintenanceWindow, error) {
+	var (
+		windows []MaintenanceWindow
+		dbError error
+	)
+	query := u.db.Where("started_at < ? AND ended_at > ?", endDate, startDate)
+	if nodeKey != "" {
+		query = query.Where("node_key IS NULL OR node_key = ?", nodeKey)
+	}
+	record := query.Order("started_at ASC").Find(&windows)
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Error("Error occurred while fetching maintenance windows - ", err)
 		}
 		return nil, dbError
 	}
 
-	return nodes, nil
+	return windows, nil
 }
 
-func (u data) findNode(key string) (Node, error) {
+func (u data) createAdjustment(adjustment *UptimeAdjustment) error {
+	return u.saveRecord(adjustment, true, "uptime adjustment")
+}
+
+// revertAdjustment marks the adjustment as reverted, an adjustment can be reverted only once
+func (u data) revertAdjustment(id uint, revertedBy string, reason string, revertedAt time.Time) error {
+	db := u.db.Model(&UptimeAdjustment{}).Where("id = ? AND reverted_at IS NULL", id).
+		UpdateColumns(map[string]interface{}{"reverted_by": revertedBy, "reverted_at": revertedAt, "revert_reason": reason})
+	if errs := db.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			log.Errorf("Error while reverting uptime adjustment %v - %v", id, err)
+		}
+		return errs[0]
+	}
+	if db.RowsAffected == 0 {
+		return errAdjustmentAlreadyReverted
+	}
+	return nil
+}
+
+func (u data) findAdjustment(id uint) (UptimeAdjustment, error) {
 	var (
-		node    Node
-		dbError error
+		adjustment UptimeAdjustment
+		dbError    error
 	)
-	record := u.db.Where("key = ?", key).Preload("MonthlyUptimes", func(db *gorm.DB) *gorm.DB { return db.Order("Monthly_Uptimes.id ASC") }).Preload("Uptimes", func(db *gorm.DB) *gorm.DB { return db.Order("Uptimes.id ASC") }).Find(&node)
+	record := u.db.Where("id = ?", id).First(&adjustment)
 	if record.RecordNotFound() {
-		return Node{}, errCannotLoadDataFromDatabase
+		return UptimeAdjustment{}, errCannotLoadDataFromDatabase
 	}
 	if errs := record.GetErrors(); len(errs) > 0 {
 		for _, err := range errs {
 			dbError = err
-			log.Errorf("Error occurred while fetching node by key %v - %v", key, err)
+			log.Errorf("Error occurred while fetching uptime adjustment %v - %v", id, err)
 		}
-		return Node{}, dbError
+		return UptimeAdjustment{}, dbError
 	}
+	return adjustment, nil
+}
 
-	return node, nil
+// findAdjustments returns adjustments overlapping the period, of every node when node key is empty
+func (u data) findAdjustments(startDate time.Time, endDate time.Time, nodeKey string, withReverted bool) ([]UptimeAdjustment, error) {
+	var (
+		adjustments []UptimeAdjustment
+		dbError     error
+	)
+	query := u.db.Where("started_at < ? AND ended_at > ?", endDate, startDate)
+	if nodeKey != "" {
+		query = query.Where("node_key = ?", nodeKey)
+	}
+	if !withReverted {
+		query = query.Where("reverted_at IS NULL")
+	}
+	record := query.Order("started_at ASC, id ASC").Find(&adjustments)
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Error("Error occurred while fetching uptime adjustments - ", err)
+		}
+		return nil, dbError
+	}
+
+	return adjustments, nil
+}
+
+// findLastUptimes returns the latest uptime of every node from the list, mapped by node key
+func (u data) findLastUptimes(nodeKeys []string) (map[string]Uptime, error) {
+	var (
+		uptimes []Uptime
+		dbError error
+	)
+	result := make(map[string]Uptime, len(nodeKeys))
+	if len(nodeKeys) == 0 {
+		return result, nil
+	}
+	record := u.db.Raw("SELECT DISTINCT ON (node_id) * FROM uptimes WHERE deleted_at IS NULL AND node_id = ANY(?) ORDER BY node_id, id DESC", pq.Array(nodeKeys)).Scan(&uptimes)
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			if err == gorm.ErrRecordNotFound {
+				return result, nil
+			}
+			dbError = err
+			log.Error("Error occurred while fetching last uptimes - ", err)
+		}
+		return nil, dbError
+	}
+	for _, uptime := range uptimes {
+		result[uptime.NodeId] = uptime
+	}
+
+	return result, nil
 }
 
-func (u data) updateAllNodesOnlineStatus(currentTime time.Time) error {
+// findPeriodUptimes returns uptimes which overlap the period, mapped by node key. Nodes without uptimes are left out.
+func (u data) findPeriodUptimes(startDate time.Time, endDate time.Time) (map[string][]Uptime, error) {
+	return overlappingUptimes(u.db, startDate, endDate)
+}
+
+// overlappingUptimes returns uptimes which overlap the period, mapped by node key. Uptimes of a node follow each
+// other, so besides uptimes started within the period only the last one started before it can reach into it, and
+// both are found through indexes on created_at.
+func overlappingUptimes(db *gorm.DB, startDate time.Time, endDate time.Time) (map[string][]Uptime, error) {
+	var (
+		uptimes []Uptime
+		dbError error
+	)
+	result := make(map[string][]Uptime)
+	record := db.Raw("SELECT * FROM uptimes WHERE deleted_at IS NULL AND created_at >= ? AND created_at < ? "+
+		"UNION ALL SELECT latest.* FROM nodes CROSS JOIN LATERAL (SELECT * FROM uptimes WHERE node_id = nodes.key AND deleted_at IS NULL "+
+		"AND created_at < ? ORDER BY created_at DESC LIMIT 1) latest WHERE latest.created_at + latest.start_time * INTERVAL '1 second' > ? "+
+		"ORDER BY node_id, id", startDate, endDate, startDate, startDate).Scan(&uptimes)
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			if err == gorm.ErrRecordNotFound {
+				return result, nil
+			}
+			dbError = err
+			log.Error("Error occurred while fetching uptimes of the period - ", err)
+		}
+		return nil, dbError
+	}
+	for _, uptime := range uptimes {
+		result[uptime.NodeId] = append(result[uptime.NodeId], uptime)
+	}
+
+	return result, nil
+}
+
+// findFirstUptimeTimes returns the start of the earliest uptime of every node, mapped by node key
+func (u data) findFirstUptimeTimes() (map[string]time.Time, error) {
+	var (
+		rows []struct {
+			NodeId    string
+			CreatedAt time.Time
+		}
+		dbError error
+	)
+	result := make(map[string]time.Time)
+	record := u.db.Raw("SELECT nodes.key AS node_id, earliest.created_at FROM nodes CROSS JOIN LATERAL (SELECT created_at FROM uptimes " +
+		"WHERE node_id = nodes.key AND deleted_at IS NULL ORDER BY created_at LIMIT 1) earliest").Scan(&rows)
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			if err == gorm.ErrRecordNotFound {
+				return result, nil
+			}
+			dbError = err
+			log.Error("Error occurred while fetching first uptimes - ", err)
+		}
+		return nil, dbError
+	}
+	for _, row := range rows {
+		result[row.NodeId] = row.CreatedAt
+	}
+
+	return result, nil
+}
+
+// useHeartbeatNonce records the nonce of a heartbeat and returns errReplayedHeartbeat when the node already used it.
+// Nonces sent before expiredBefore can no longer pass the freshness check and are removed.
+func (u data) useHeartbeatNonce(nodeKey string, nonce string, sent time.Time, expiredBefore time.Time) error {
+	if err := u.db.Exec("DELETE FROM heartbeat_nonces WHERE sent_at < ?", expiredBefore).Error; err != nil {
+		log.Error("Error occurred while removing expired heartbeat nonces - ", err)
+		return err
+	}
+	record := u.db.Exec("INSERT INTO heartbeat_nonces (node_key, nonce, sent_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING", nodeKey, nonce, sent)
+	if record.Error != nil {
+		log.Errorf("Error occurred while storing heartbeat nonce of node %v - %v", nodeKey, record.Error)
+		return record.Error
+	}
+	if record.RowsAffected == 0 {
+		return errReplayedHeartbeat
+	}
+	return nil
+}
+
+// saveCollection upserts nodes, creates and extends uptimes and marks missing nodes offline in a single transaction
+func (u data) saveCollection(batch *collectionBatch) error {
 	db := u.db.Begin()
-	var dbError error
-	for _, err := range db.Exec("UPDATE nodes set online=? where online=? and last_check < ?;", false, true, currentTime).GetErrors() {
-		dbError = err
-		log.Error("Error while updating nodes online status: ", err)
+	now := time.Now()
+	dbError := lockCollection(db, batch)
+	var lastHeartbeat *time.Time
+	if batch.Heartbeat {
+		lastHeartbeat = &batch.CheckTime
+	}
+
+	for start := 0; start < len(batch.OnlineNodes) && dbError == nil; start += bulkChunkSize {
+		chunk := batch.OnlineNodes[start:minInt(start+bulkChunkSize, len(batch.OnlineNodes))]
+		values := make([]string, 0, len(chunk))
+		args := make([]interface{}, 0, len(chunk)*5)
+		for _, key := range chunk {
+			values = append(values, "(?, true, ?, ?, ?, ?)")
+			args = append(args, key, batch.CheckTime, lastHeartbeat, now, now)
+		}
+		// heartbeats do not move last check, which tracks when the collector observed nodes
+		update := "last_check = EXCLUDED.last_check"
+		if batch.Heartbeat {
+			update = "last_heartbeat = EXCLUDED.last_heartbeat"
+		}
+		query := "INSERT INTO nodes (key, online, last_check, last_heartbeat, created_at, updated_at) VALUES " + strings.Join(values, ", ") +
+			" ON CONFLICT (key) DO UPDATE SET online = EXCLUDED.online, " + update + ", updated_at = EXCLUDED.updated_at"
+		dbError = execBulk(db, "upserting nodes", query, args)
+	}
+
+	for start := 0; start < len(batch.NewUptimes) && dbError == nil; start += bulkChunkSize {
+		chunk := batch.NewUptimes[start:minInt(start+bulkChunkSize, len(batch.NewUptimes))]
+		values := make([]string, 0, len(chunk))
+		args := make([]interface{}, 0, len(chunk)*4)
+		for _, uptime := range chunk {
+			values = append(values, "(?, ?, ?, ?)")
+			args = append(args, uptime.NodeId, uptime.StartTime, uptime.CreatedAt, now)
+		}
+		query := "INSERT INTO uptimes (node_id, start_time, created_at, updated_at) VALUES " + strings.Join(values, ", ")
+		dbError = execBulk(db, "creating uptimes", query, args)
+	}
+
+	for start := 0; start < len(batch.ExtendedUptimes) && dbError == nil; start += bulkChunkSize {
+		chunk := batch.ExtendedUptimes[start:minInt(start+bulkChunkSize, len(batch.ExtendedUptimes))]
+		values := make([]string, 0, len(chunk))
+		args := []interface{}{now}
+		for _, uptime := range chunk {
+			values = append(values, "(?::integer, ?::integer)")
+			args = append(args, uptime.Id, uptime.StartTime)
+		}
+		query := "UPDATE uptimes SET start_time = v.start_time, updated_at = ? FROM (VALUES " + strings.Join(values, ", ") +
+			") AS v(id, start_time) WHERE uptimes.id = v.id"
+		dbError = execBulk(db, "extending uptimes", query, args)
 	}
+
+	for start := 0; start < len(batch.ClosedMetadata) && dbError == nil; start += bulkChunkSize {
+		chunk := batch.ClosedMetadata[start:minInt(start+bulkChunkSize, len(batch.ClosedMetadata))]
+		dbError = execBulk(db, "closing node metadata", "UPDATE node_metadata SET valid_to = ? WHERE id IN (?)", []interface{}{batch.CheckTime, chunk})
+	}
+
+	for start := 0; start < len(batch.NewMetadata) && dbError == nil; start += bulkChunkSize {
+		chunk := batch.NewMetadata[start:minInt(start+bulkChunkSize, len(batch.NewMetadata))]
+		values := make([]string, 0, len(chunk))
+		args := make([]interface{}, 0, len(chunk)*5)
+		for _, metadata := range chunk {
+			values = append(values, "(?, ?, ?, ?, ?)")
+			args = append(args, metadata.NodeId, metadata.Attribute, metadata.Value, metadata.ValidFrom, now)
+		}
+		query := "INSERT INTO node_metadata (node_id, attribute, value, valid_from, created_at) VALUES " + strings.Join(values, ", ")
+		dbError = execBulk(db, "creating node metadata", query, args)
+	}
+
+	for start := 0; start < len(batch.Rollups) && dbError == nil; start += bulkChunkSize {
+		chunk := batch.Rollups[start:minInt(start+bulkChunkSize, len(batch.Rollups))]
+		query, args := uptimeRollupsInsert(chunk, now)
+		query += " ON CONFLICT (node_id, granularity, bucket_start) DO UPDATE SET " +
+			"uptime_seconds = uptime_rollups.uptime_seconds + EXCLUDED.uptime_seconds, updated_at = EXCLUDED.updated_at"
+		dbError = execBulk(db, "updating uptime rollups", query, args)
+	}
+
+	// a heartbeat tells nothing about other nodes and it is not a collection run to sample the fleet at,
+	// nodes it brought online are counted by the sample of the next collection run
+	if dbError == nil && !batch.Heartbeat {
+		var offline []string
+		offline, dbError = markNodesOffline(db, batch.CheckTime, batch.CheckTime.Add(-heartbeatOfflineAfter()))
+		batch.MarkedOffline = int64(len(offline))
+		for _, key := range offline {
+			batch.Events = append(batch.Events, NodeEvent{
+				NodeId:     key,
+				Type:       EventWentOffline,
+				OccurredAt: batch.CheckTime,
+				DetectedAt: batch.CheckTime,
+				RunId:      batch.RunId,
+			})
+		}
+	}
+
+	if dbError == nil && !batch.Heartbeat {
+		query := "INSERT INTO fleet_samples (run_id, sampled_at, total, online, offline, new_nodes, went_offline, restarted, created_at) " +
+			"SELECT ?, ?, COUNT(*), COUNT(*) FILTER (WHERE online), COUNT(*) FILTER (WHERE NOT online), ?, ?, ?, ? FROM nodes WHERE deleted_at IS NULL"
+		dbError = execBulk(db, "recording fleet sample", query, []interface{}{batch.RunId, batch.CheckTime, batch.NewNodes, batch.MarkedOffline, batch.Restarts, now})
+	}
+
+	for start := 0; start < len(batch.Events) && dbError == nil; start += bulkChunkSize {
+		chunk := batch.Events[start:minInt(start+bulkChunkSize, len(batch.Events))]
+		values := make([]string, 0, len(chunk))
+		args := make([]interface{}, 0, len(chunk)*6)
+		for _, event := range chunk {
+			values = append(values, "(?, ?, ?, ?, ?, ?)")
+			args = append(args, event.NodeId, event.Type, event.OccurredAt, event.DetectedAt, event.RunId, now)
+		}
+		query := "INSERT INTO node_events (node_id, type, occurred_at, detected_at, run_id, created_at) VALUES " + strings.Join(values, ", ")
+		dbError = execBulk(db, "creating node events", query, args)
+	}
+
 	if dbError != nil {
 		db.Rollback()
 		return dbError
 	}
 	db.Commit()
+
+	return nil
+}
+
+func execBulk(db *gorm.DB, operation string, query string, args []interface{}) error {
+	var dbError error
+	for _, err := range db.Exec(query, args...).GetErrors() {
+		dbError = err
+		log.Errorf("Error while %v in DB - %v", operation, err)
+	}
+	return dbError
+}
+
+func uptimeRollupsInsert(rollups []UptimeRollup, now time.Time) (string, []interface{}) {
+	values := make([]string, 0, len(rollups))
+	args := make([]interface{}, 0, len(rollups)*6)
+	for _, rollup := range rollups {
+		values = append(values, "(?, ?, ?, ?, ?, ?)")
+		args = append(args, rollup.NodeId, rollup.Granularity, rollup.BucketStart, rollup.UptimeSeconds, now, now)
+	}
+	query := "INSERT INTO uptime_rollups (node_id, granularity, bucket_start, uptime_seconds, created_at, updated_at) VALUES " + strings.Join(values, ", ")
+	return query, args
+}
+
+// markNodesOffline marks nodes not seen since checkTime and without a heartbeat since heartbeatTime offline
+// and returns their keys
+func markNodesOffline(db *gorm.DB, checkTime time.Time, heartbeatTime time.Time) ([]string, error) {
+	rows, err := db.Raw("UPDATE nodes SET online = ? WHERE online = ? AND last_check < ? AND (last_heartbeat IS NULL OR last_heartbeat < ?) RETURNING key",
+		false, true, checkTime, heartbeatTime).Rows()
+	if err != nil {
+		log.Error("Error while updating nodes online status: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// lockCollection serializes writes of collection runs and heartbeats of all instances within the transaction and
+// checks that last uptimes the batch was computed from were not changed by a concurrent write
+func lockCollection(db *gorm.DB, batch *collectionBatch) error {
+	if err := execBulk(db, "locking collection", "SELECT pg_advisory_xact_lock(?)", []interface{}{collectionLockId}); err != nil {
+		return err
+	}
+	var current []Uptime
+	if len(batch.OnlineNodes) > 0 {
+		record := db.Raw("SELECT DISTINCT ON (node_id) * FROM uptimes WHERE deleted_at IS NULL AND node_id = ANY(?) ORDER BY node_id, id DESC",
+			pq.Array(batch.OnlineNodes)).Scan(&current)
+		if err := record.Error; err != nil && err != gorm.ErrRecordNotFound {
+			log.Error("Error occurred while checking last uptimes - ", err)
+			return err
+		}
+	}
+	if len(current) != len(batch.LastUptimes) {
+		return errCollectionConflict
+	}
+	for _, uptime := range current {
+		if last, found := batch.LastUptimes[uptime.NodeId]; !found || last.Id != uptime.Id || last.StartTime != uptime.StartTime {
+			return errCollectionConflict
+		}
+	}
 	return nil
 }
 
-func (u data) createMonthlyUptime(monthlyUptime *MonthlyUptime) error {
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func (u data) createCollectionRun(run *CollectionRun) error {
 	db := u.db.Begin()
 	var dbError error
-	for _, err := range db.Create(monthlyUptime).GetErrors() {
+	for _, err := range db.Create(run).GetErrors() {
 		dbError = err
-		log.Error("Error while creating new monthly uptime in DB ", err)
+		log.Error("Error while creating new collection run in DB ", err)
 	}
 	if dbError != nil {
 		db.Rollback()
@@ -189,3 +679,834 @@ func (u data) createMonthlyUptime(monthlyUptime *MonthlyUptime) error {
 
 	return nil
 }
+
+// findCollectionRuns returns a page of runs, newest first, together with the total number of runs
+func (u data) findCollectionRuns(page int, pageSize int) ([]CollectionRun, int, error) {
+	var (
+		runs    []CollectionRun
+		total   int
+		dbError error
+	)
+	if errs := u.db.Model(&CollectionRun{}).Count(&total).GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Error("Error occurred while counting collection runs - ", err)
+		}
+		return nil, 0, dbError
+	}
+	record := u.db.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&runs)
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Error("Error occurred while fetching collection runs - ", err)
+		}
+		return nil, 0, dbError
+	}
+
+	return runs, total, nil
+}
+
+func (u data) updateCollectionRun(run *CollectionRun) error {
+	var dbError error
+	for _, err := range u.db.Save(run).GetErrors() {
+		dbError = err
+		log.Error("Error while updating collection run in DB ", err)
+	}
+	return dbError
+}
+
+func (u data) findCollectionRun(id uint) (CollectionRun, error) {
+	var (
+		run     CollectionRun
+		dbError error
+	)
+	record := u.db.Where("id = ?", id).First(&run)
+	if record.RecordNotFound() {
+		return CollectionRun{}, errCannotLoadDataFromDatabase
+	}
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Errorf("Error occurred while fetching collection run %v - %v", id, err)
+		}
+		return CollectionRun{}, dbError
+	}
+
+	return run, nil
+}
+
+// findCurrentMetadata returns currently valid metadata of every node from the list, mapped by node key and attribute
+func (u data) findCurrentMetadata(nodeKeys []string) (map[string]map[string]NodeMetadata, error) {
+	var (
+		metadata []NodeMetadata
+		dbError  error
+	)
+	result := make(map[string]map[string]NodeMetadata)
+	if len(nodeKeys) == 0 {
+		return result, nil
+	}
+	record := u.db.Where("valid_to IS NULL AND node_id = ANY(?)", pq.Array(nodeKeys)).Find(&metadata)
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Error("Error occurred while fetching current node metadata - ", err)
+		}
+		return nil, dbError
+	}
+	for _, m := range metadata {
+		if result[m.NodeId] == nil {
+			result[m.NodeId] = make(map[string]NodeMetadata)
+		}
+		result[m.NodeId][m.Attribute] = m
+	}
+
+	return result, nil
+}
+
+func (u data) findNodeMetadata(nodeKey string) ([]NodeMetadata, error) {
+	var (
+		metadata []NodeMetadata
+		dbError  error
+	)
+	record := u.db.Where("node_id = ?", nodeKey).Order("attribute ASC, valid_from ASC").Find(&metadata)
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Errorf("Error occurred while fetching metadata for node %v - %v", nodeKey, err)
+		}
+		return nil, dbError
+	}
+
+	return metadata, nil
+}
+
+// findOfflineNodes returns keys from the list of nodes which are currently marked offline
+func (u data) findOfflineNodes(nodeKeys []string) (map[string]bool, error) {
+	var offline []string
+	result := make(map[string]bool)
+	if len(nodeKeys) == 0 {
+		return result, nil
+	}
+	if err := u.db.Model(&Node{}).Where("online = ? AND key = ANY(?)", false, pq.Array(nodeKeys)).Pluck("key", &offline).Error; err != nil {
+		log.Error("Error occurred while fetching offline nodes - ", err)
+		return nil, err
+	}
+	for _, key := range offline {
+		result[key] = true
+	}
+
+	return result, nil
+}
+
+func (u data) findNodeEvents(nodeKey string, startDate time.Time, endDate time.Time) ([]NodeEvent, error) {
+	var (
+		events  []NodeEvent
+		dbError error
+	)
+	record := u.db.Where("node_id = ? AND occurred_at >= ? AND occurred_at < ?", nodeKey, startDate, endDate).Order("occurred_at ASC, id ASC").Find(&events)
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Errorf("Error occurred while fetching events for node %v - %v", nodeKey, err)
+		}
+		return nil, dbError
+	}
+
+	return events, nil
+}
+
+// countNodeEvents returns the number of events of the type per node within the period
+func (u data) countNodeEvents(eventType string, startDate time.Time, endDate time.Time) (map[string]int, error) {
+	rows, err := u.db.Model(&NodeEvent{}).Select("node_id, COUNT(*)").
+		Where("type = ? AND occurred_at >= ? AND occurred_at < ?", eventType, startDate, endDate).Group("node_id").Rows()
+	if err != nil {
+		log.Errorf("Error occurred while counting %v events - %v", eventType, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var (
+			nodeKey string
+			count   int
+		)
+		if err := rows.Scan(&nodeKey, &count); err != nil {
+			log.Errorf("Error occurred while counting %v events - %v", eventType, err)
+			return nil, err
+		}
+		counts[nodeKey] = count
+	}
+	return counts, rows.Err()
+}
+
+// countUptimeRestarts counts uptimes started within the period per node. The first start of a node is not a restart,
+// it precedes the node record unlike starts following compacted uptimes.
+func (u data) countUptimeRestarts(startDate time.Time, endDate time.Time) (map[string]int, error) {
+	rows, err := u.db.Raw("SELECT uptimes.node_id, COUNT(*) FROM uptimes JOIN nodes ON nodes.key = uptimes.node_id "+
+		"WHERE uptimes.deleted_at IS NULL AND uptimes.created_at >= ? AND uptimes.created_at < ? AND (uptimes.created_at > nodes.created_at "+
+		"OR EXISTS (SELECT 1 FROM uptimes earlier WHERE earlier.node_id = uptimes.node_id AND earlier.deleted_at IS NULL AND earlier.id < uptimes.id)) "+
+		"GROUP BY uptimes.node_id", startDate, endDate).Rows()
+	if err != nil {
+		log.Error("Error occurred while counting restarts - ", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var (
+			nodeKey string
+			count   int
+		)
+		if err := rows.Scan(&nodeKey, &count); err != nil {
+			log.Error("Error occurred while counting restarts - ", err)
+			return nil, err
+		}
+		counts[nodeKey] = count
+	}
+	return counts, rows.Err()
+}
+
+func (u data) findMonthlyUptimes(year int, month int) ([]MonthlyUptime, error) {
+	var (
+		monthlyUptimes []MonthlyUptime
+		dbError        error
+	)
+	record := u.db.Where("year = ? AND month = ?", year, month).Order("node_id ASC").Find(&monthlyUptimes)
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Errorf("Error occurred while fetching monthly uptimes for %v/%v - %v", month, year, err)
+		}
+		return nil, dbError
+	}
+
+	return monthlyUptimes, nil
+}
+
+func (u data) findFirstUptimeTime() (time.Time, error) {
+	var first pq.NullTime
+	if err := u.db.Model(&Uptime{}).Select("MIN(created_at)").Row().Scan(&first); err != nil {
+		log.Error("Error occurred while fetching first uptime time - ", err)
+		return time.Time{}, err
+	}
+	if !first.Valid {
+		return time.Time{}, errCannotLoadDataFromDatabase
+	}
+
+	return first.Time, nil
+}
+
+func (u data) findClosedMonths() ([]ClosedMonth, error) {
+	var (
+		closedMonths []ClosedMonth
+		dbError      error
+	)
+	record := u.db.Order("year ASC, month ASC").Find(&closedMonths)
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Error("Error occurred while fetching closed months - ", err)
+		}
+		return nil, dbError
+	}
+
+	return closedMonths, nil
+}
+
+func (u data) saveClosedMonth(closedMonth *ClosedMonth) error {
+	var dbError error
+	now := time.Now()
+	query := "INSERT INTO closed_months (year, month, closed_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?) " +
+		"ON CONFLICT (year, month) DO UPDATE SET closed_at = EXCLUDED.closed_at, updated_at = EXCLUDED.updated_at"
+	for _, err := range u.db.Exec(query, closedMonth.Year, closedMonth.Month, closedMonth.ClosedAt, now, now).GetErrors() {
+		dbError = err
+		log.Error("Error while saving closed month in DB ", err)
+	}
+	return dbError
+}
+
+// createMonthlyReport appends the report to the chain, the unique previous hash prevents forks of the chain
+func (u data) createMonthlyReport(report *MonthlyReport) error {
+	var dbError error
+	for _, err := range u.db.Create(report).GetErrors() {
+		dbError = err
+		log.Errorf("Error while publishing monthly report %v/%v in DB - %v", report.Month, report.Year, err)
+	}
+	if isUniqueViolation(dbError) {
+		return errReportChainConflict
+	}
+	return dbError
+}
+
+// findMonthlyReports returns the whole chain in the order of publication, report contents are loaded only when requested
+func (u data) findMonthlyReports(withContent bool) ([]MonthlyReport, error) {
+	var (
+		reports []MonthlyReport
+		dbError error
+	)
+	query := u.db.Order("id ASC")
+	if !withContent {
+		query = query.Select("id, year, month, revision, timezone, report_hash, previous_hash, hash, merkle_root, published_at")
+	}
+	record := query.Find(&reports)
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Error("Error occurred while fetching monthly reports - ", err)
+		}
+		return nil, dbError
+	}
+
+	return reports, nil
+}
+
+// findMonthlyReport returns the latest revision of the month report
+func (u data) findMonthlyReport(year int, month int) (MonthlyReport, error) {
+	var (
+		report  MonthlyReport
+		dbError error
+	)
+	record := u.db.Where("year = ? AND month = ?", year, month).Order("revision DESC").First(&report)
+	if record.RecordNotFound() {
+		return MonthlyReport{}, errCannotLoadDataFromDatabase
+	}
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Errorf("Error occurred while fetching monthly report %v/%v - %v", month, year, err)
+		}
+		return MonthlyReport{}, dbError
+	}
+	return report, nil
+}
+
+func (u data) findUptimeRollups(nodeKeys []string, granularity string, startDate time.Time, endDate time.Time) ([]UptimeRollup, error) {
+	var (
+		rollups []UptimeRollup
+		dbError error
+	)
+	record := u.db.Where("granularity = ? AND node_id = ANY(?) AND bucket_start >= ? AND bucket_start < ?", granularity, pq.Array(nodeKeys), startDate, endDate).
+		Order("node_id ASC, bucket_start ASC").Find(&rollups)
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Error("Error occurred while fetching uptime rollups - ", err)
+		}
+		return nil, dbError
+	}
+
+	return rollups, nil
+}
+
+// rebuildUptimeRollups replaces all rollups with buckets starting within the period by rollups built from uptimes
+// which overlap it. Uptimes are read under the collection lock within the same transaction, so that increments
+// written by concurrent collection runs are not lost.
+func (u data) rebuildUptimeRollups(startDate time.Time, endDate time.Time, build func(uptimes map[string][]Uptime) []UptimeRollup) error {
+	db := u.db.Begin()
+	now := time.Now()
+	dbError := execBulk(db, "locking collection", "SELECT pg_advisory_xact_lock(?)", []interface{}{collectionLockId})
+	var uptimes map[string][]Uptime
+	if dbError == nil {
+		uptimes, dbError = overlappingUptimes(db, startDate, endDate)
+	}
+	var rollups []UptimeRollup
+	if dbError == nil {
+		rollups = build(uptimes)
+		dbError = execBulk(db, "removing uptime rollups", "DELETE FROM uptime_rollups WHERE bucket_start >= ? AND bucket_start < ?", []interface{}{startDate, endDate})
+	}
+	for start := 0; start < len(rollups) && dbError == nil; start += bulkChunkSize {
+		query, args := uptimeRollupsInsert(rollups[start:minInt(start+bulkChunkSize, len(rollups))], now)
+		dbError = execBulk(db, "creating uptime rollups", query, args)
+	}
+	if dbError != nil {
+		db.Rollback()
+		return dbError
+	}
+	db.Commit()
+
+	return nil
+}
+
+// copyMaintenanceAndAdjustments copies maintenance windows and uptime adjustments from the schema of the same database
+func (u data) copyMaintenanceAndAdjustments(schema string) error {
+	db := u.db.Begin()
+	var dbError error
+	for _, table := range []string{"maintenance_windows", "uptime_adjustments"} {
+		if dbError == nil {
+			dbError = execBulk(db, "copying "+table, fmt.Sprintf("INSERT INTO %[1]s SELECT * FROM %[2]q.%[1]s", table, schema), nil)
+		}
+		if dbError == nil {
+			dbError = execBulk(db, "resetting ids of "+table,
+				fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM %[1]s", table), nil)
+		}
+	}
+	if dbError != nil {
+		db.Rollback()
+		return dbError
+	}
+	db.Commit()
+
+	return nil
+}
+
+// findSchemaCollectionRuns returns all collection runs stored in the given schema ordered by their start
+func (u data) findSchemaCollectionRuns(schema string) ([]CollectionRun, error) {
+	var (
+		runs    []CollectionRun
+		dbError error
+	)
+	record := u.db.Raw(fmt.Sprintf("SELECT * FROM %q.collection_runs WHERE deleted_at IS NULL ORDER BY started_at, id", schema)).Scan(&runs)
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			if err == gorm.ErrRecordNotFound {
+				return nil, nil
+			}
+			dbError = err
+			log.Errorf("Error occurred while fetching collection runs of schema %v - %v", schema, err)
+		}
+		return nil, dbError
+	}
+
+	return runs, nil
+}
+
+// findLastCompaction returns the compaction with the latest boundary
+func (u data) findLastCompaction() (UptimeCompaction, error) {
+	var (
+		compaction UptimeCompaction
+		dbError    error
+	)
+	record := u.db.Order("compacted_before DESC").First(&compaction)
+	if record.RecordNotFound() {
+		return UptimeCompaction{}, errCannotLoadDataFromDatabase
+	}
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Error("Error occurred while fetching the last uptime compaction - ", err)
+		}
+		return UptimeCompaction{}, dbError
+	}
+	return compaction, nil
+}
+
+// findCompactableUptimes passes every uptime which ended before the boundary to the each function and returns
+// their ids. The last uptime of every node is never returned, as it is extended by following collection runs.
+func (u data) findCompactableUptimes(boundary time.Time, each func(uptime Uptime) error) ([]uint, error) {
+	rows, err := u.db.Raw("SELECT * FROM uptimes WHERE deleted_at IS NULL AND created_at + start_time * INTERVAL '1 second' <= ? "+
+		"AND id NOT IN (SELECT MAX(id) FROM uptimes WHERE deleted_at IS NULL GROUP BY node_id) ORDER BY id ASC", boundary).Rows()
+	if err != nil {
+		log.Error("Error occurred while fetching compactable uptimes - ", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []uint
+	for rows.Next() {
+		var uptime Uptime
+		if err := u.db.ScanRows(rows, &uptime); err != nil {
+			return nil, err
+		}
+		if err := each(uptime); err != nil {
+			return nil, err
+		}
+		ids = append(ids, uptime.Id)
+	}
+	return ids, rows.Err()
+}
+
+// compactUptimes permanently removes uptimes replaced by rollups and records the compaction in one transaction
+func (u data) compactUptimes(ids []uint, compaction *UptimeCompaction) error {
+	db := u.db.Begin()
+	var dbError error
+	for start := 0; start < len(ids) && dbError == nil; start += bulkChunkSize {
+		chunk := ids[start:minInt(start+bulkChunkSize, len(ids))]
+		dbError = execBulk(db, "removing compacted uptimes", "DELETE FROM uptimes WHERE id = ANY(?)", []interface{}{pq.Array(chunk)})
+	}
+	if dbError == nil {
+		for _, err := range db.Create(compaction).GetErrors() {
+			dbError = err
+			log.Error("Error while creating uptime compaction in DB ", err)
+		}
+	}
+	if dbError != nil {
+		db.Rollback()
+		return dbError
+	}
+	db.Commit()
+
+	return nil
+}
+
+// createPayoutRun stores the run together with its entries in one transaction
+func (u data) createPayoutRun(run *PayoutRun) error {
+	entries := run.Entries
+	run.Entries = nil
+	defer func() { run.Entries = entries }()
+
+	db := u.db.Begin()
+	var dbError error
+	for _, err := range db.Create(run).GetErrors() {
+		dbError = err
+		log.Error("Error while creating payout run in DB ", err)
+	}
+	now := time.Now()
+	for start := 0; start < len(entries) && dbError == nil; start += bulkChunkSize {
+		chunk := entries[start:minInt(start+bulkChunkSize, len(entries))]
+		values := make([]string, 0, len(chunk))
+		args := make([]interface{}, 0, len(chunk)*6)
+		for i := range chunk {
+			chunk[i].RunId = run.Id
+			values = append(values, "(?, ?, ?, ?, ?, ?)")
+			args = append(args, chunk[i].RunId, chunk[i].NodeId, chunk[i].Address, chunk[i].Weight, chunk[i].Amount, now)
+		}
+		query := "INSERT INTO payout_entries (run_id, node_id, address, weight, amount, created_at) VALUES " + strings.Join(values, ", ")
+		dbError = execBulk(db, "creating payout entries", query, args)
+	}
+	if dbError != nil {
+		db.Rollback()
+		return dbError
+	}
+	db.Commit()
+
+	return nil
+}
+
+// findPayoutRuns returns a page of payout runs without entries, newest first, together with the total number of runs
+func (u data) findPayoutRuns(page int, pageSize int) ([]PayoutRun, int, error) {
+	var (
+		runs    []PayoutRun
+		total   int
+		dbError error
+	)
+	if errs := u.db.Model(&PayoutRun{}).Count(&total).GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Error("Error occurred while counting payout runs - ", err)
+		}
+		return nil, 0, dbError
+	}
+	record := u.db.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&runs)
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Error("Error occurred while fetching payout runs - ", err)
+		}
+		return nil, 0, dbError
+	}
+
+	return runs, total, nil
+}
+
+func (u data) findPayoutRun(id uint) (PayoutRun, error) {
+	var (
+		run     PayoutRun
+		dbError error
+	)
+	record := u.db.Where("id = ?", id).Preload("Entries", func(db *gorm.DB) *gorm.DB { return db.Order("payout_entries.node_id ASC") }).First(&run)
+	if record.RecordNotFound() {
+		return PayoutRun{}, errCannotLoadDataFromDatabase
+	}
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Errorf("Error occurred while fetching payout run %v - %v", id, err)
+		}
+		return PayoutRun{}, dbError
+	}
+
+	return run, nil
+}
+
+// fleetBucket holds fleet samples aggregated within a single bucket
+type fleetBucket struct {
+	BucketStart    time.Time
+	Samples        int
+	TotalMin       float64
+	TotalMax       float64
+	TotalAvg       float64
+	OnlineMin      float64
+	OnlineMax      float64
+	OnlineAvg      float64
+	OfflineMin     float64
+	OfflineMax     float64
+	OfflineAvg     float64
+	NewMin         float64
+	NewMax         float64
+	NewAvg         float64
+	WentOfflineMin float64
+	WentOfflineMax float64
+	WentOfflineAvg float64
+	RestartedMin   float64
+	RestartedMax   float64
+	RestartedAvg   float64
+}
+
+// findFleetSeries aggregates fleet samples within the period into buckets of the resolution aligned to unix epoch
+func (u data) findFleetSeries(startDate time.Time, endDate time.Time, resolution time.Duration) ([]fleetBucket, error) {
+	var (
+		buckets []fleetBucket
+		dbError error
+	)
+	var columns []string
+	for _, column := range []string{"total", "online", "offline", "new_nodes", "went_offline", "restarted"} {
+		alias := strings.TrimSuffix(column, "_nodes")
+		columns = append(columns, fmt.Sprintf("MIN(%[1]s) AS %[2]s_min, MAX(%[1]s) AS %[2]s_max, AVG(%[1]s) AS %[2]s_avg", column, alias))
+	}
+	seconds := int64(resolution.Seconds())
+	query := "SELECT to_timestamp(floor(extract(epoch FROM sampled_at) / ?) * ?) AS bucket_start, COUNT(*) AS samples, " +
+		strings.Join(columns, ", ") + " FROM fleet_samples WHERE sampled_at >= ? AND sampled_at < ? GROUP BY 1 ORDER BY 1"
+	record := u.db.Raw(query, seconds, seconds, startDate, endDate).Scan(&buckets)
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Error("Error occurred while fetching fleet samples - ", err)
+		}
+		return nil, dbError
+	}
+
+	return buckets, nil
+}
+
+// isUniqueViolation tells whether the error was caused by a unique constraint
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
+
+// saveRecord creates the record when it has no id yet and updates it otherwise
+func (u data) saveRecord(record interface{}, isNew bool, name string) error {
+	var db *gorm.DB
+	if isNew {
+		db = u.db.Create(record)
+	} else {
+		db = u.db.Save(record)
+	}
+	var dbError error
+	for _, err := range db.GetErrors() {
+		dbError = err
+		log.Errorf("Error while saving %v in DB - %v", name, err)
+	}
+	if isUniqueViolation(dbError) {
+		return errNameAlreadyUsed
+	}
+	return dbError
+}
+
+// deleteRecord deletes the record with the id, memberships are removed by the database
+func (u data) deleteRecord(record interface{}, id uint, name string) error {
+	db := u.db.Where("id = ?", id).Delete(record)
+	if errs := db.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			log.Errorf("Error while deleting %v %v - %v", name, id, err)
+		}
+		return errs[0]
+	}
+	if db.RowsAffected == 0 {
+		return errCannotLoadDataFromDatabase
+	}
+	return nil
+}
+
+// memberKeys returns node keys from the membership table mapped by the owner or group id
+func (u data) memberKeys(table string, column string, ids []uint) (map[uint][]string, error) {
+	members := make(map[uint][]string)
+	if len(ids) == 0 {
+		return members, nil
+	}
+	query := fmt.Sprintf("SELECT %[2]s, node_id FROM %[1]s WHERE %[2]s = ANY(?) ORDER BY node_id ASC", table, column)
+	rows, err := u.db.Raw(query, pq.Array(ids)).Rows()
+	if err != nil {
+		log.Errorf("Error occurred while fetching nodes from %v - %v", table, err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			id      uint
+			nodeKey string
+		)
+		if err := rows.Scan(&id, &nodeKey); err != nil {
+			return nil, err
+		}
+		members[id] = append(members[id], nodeKey)
+	}
+	return members, rows.Err()
+}
+
+func (u data) saveOwner(owner *Owner) error {
+	return u.saveRecord(owner, owner.Id == 0, "owner")
+}
+
+func (u data) deleteOwner(id uint) error {
+	return u.deleteRecord(&Owner{}, id, "owner")
+}
+
+func (u data) findOwners() ([]Owner, error) {
+	var (
+		owners  []Owner
+		dbError error
+	)
+	if errs := u.db.Order("name ASC").Find(&owners).GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Error("Error occurred while fetching owners - ", err)
+		}
+		return nil, dbError
+	}
+	ids := make([]uint, 0, len(owners))
+	for _, owner := range owners {
+		ids = append(ids, owner.Id)
+	}
+	members, err := u.memberKeys("owner_nodes", "owner_id", ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range owners {
+		owners[i].Nodes = members[owners[i].Id]
+	}
+	return owners, nil
+}
+
+func (u data) findOwner(id uint) (Owner, error) {
+	var (
+		owner   Owner
+		dbError error
+	)
+	record := u.db.Where("id = ?", id).First(&owner)
+	if record.RecordNotFound() {
+		return Owner{}, errCannotLoadDataFromDatabase
+	}
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Errorf("Error occurred while fetching owner %v - %v", id, err)
+		}
+		return Owner{}, dbError
+	}
+	members, err := u.memberKeys("owner_nodes", "owner_id", []uint{id})
+	if err != nil {
+		return Owner{}, err
+	}
+	owner.Nodes = members[id]
+	return owner, nil
+}
+
+// addOwnerNodes assigns nodes to the owner, nodes owned by someone else are moved
+func (u data) addOwnerNodes(ownerId uint, nodeKeys []string, admin string) error {
+	values := make([]string, 0, len(nodeKeys))
+	args := make([]interface{}, 0, len(nodeKeys)*4)
+	now := time.Now()
+	for _, key := range nodeKeys {
+		values = append(values, "(?, ?, ?, ?)")
+		args = append(args, key, ownerId, admin, now)
+	}
+	query := "INSERT INTO owner_nodes (node_id, owner_id, added_by, created_at) VALUES " + strings.Join(values, ", ") +
+		" ON CONFLICT (node_id) DO UPDATE SET owner_id = EXCLUDED.owner_id, added_by = EXCLUDED.added_by, created_at = EXCLUDED.created_at"
+	return execBulk(u.db, "assigning nodes to owner", query, args)
+}
+
+func (u data) removeOwnerNode(ownerId uint, nodeKey string) error {
+	return execBulk(u.db, "removing node from owner", "DELETE FROM owner_nodes WHERE owner_id = ? AND node_id = ?", []interface{}{ownerId, nodeKey})
+}
+
+// findNodeOwners returns owners of nodes from the list mapped by node key, nodes without owner are left out
+func (u data) findNodeOwners(nodeKeys []string) (map[string]Owner, error) {
+	result := make(map[string]Owner)
+	if len(nodeKeys) == 0 {
+		return result, nil
+	}
+	rows, err := u.db.Raw("SELECT owner_nodes.node_id, owners.id, owners.name, owners.address FROM owner_nodes "+
+		"JOIN owners ON owners.id = owner_nodes.owner_id WHERE owner_nodes.node_id = ANY(?)", pq.Array(nodeKeys)).Rows()
+	if err != nil {
+		log.Error("Error occurred while fetching node owners - ", err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			nodeKey string
+			owner   Owner
+		)
+		if err := rows.Scan(&nodeKey, &owner.Id, &owner.Name, &owner.Address); err != nil {
+			return nil, err
+		}
+		result[nodeKey] = owner
+	}
+	return result, rows.Err()
+}
+
+func (u data) saveGroup(group *NodeGroup) error {
+	return u.saveRecord(group, group.Id == 0, "node group")
+}
+
+func (u data) deleteGroup(id uint) error {
+	return u.deleteRecord(&NodeGroup{}, id, "node group")
+}
+
+func (u data) findGroups() ([]NodeGroup, error) {
+	var (
+		groups  []NodeGroup
+		dbError error
+	)
+	if errs := u.db.Order("name ASC").Find(&groups).GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Error("Error occurred while fetching node groups - ", err)
+		}
+		return nil, dbError
+	}
+	ids := make([]uint, 0, len(groups))
+	for _, group := range groups {
+		ids = append(ids, group.Id)
+	}
+	members, err := u.memberKeys("group_nodes", "group_id", ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		groups[i].Nodes = members[groups[i].Id]
+	}
+	return groups, nil
+}
+
+func (u data) findGroup(id uint) (NodeGroup, error) {
+	var (
+		group   NodeGroup
+		dbError error
+	)
+	record := u.db.Where("id = ?", id).First(&group)
+	if record.RecordNotFound() {
+		return NodeGroup{}, errCannotLoadDataFromDatabase
+	}
+	if errs := record.GetErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			dbError = err
+			log.Errorf("Error occurred while fetching node group %v - %v", id, err)
+		}
+		return NodeGroup{}, dbError
+	}
+	members, err := u.memberKeys("group_nodes", "group_id", []uint{id})
+	if err != nil {
+		return NodeGroup{}, err
+	}
+	group.Nodes = members[id]
+	return group, nil
+}
+
+func (u data) addGroupNodes(groupId uint, nodeKeys []string, admin string) error {
+	values := make([]string, 0, len(nodeKeys))
+	args := make([]interface{}, 0, len(nodeKeys)*4)
+	now := time.Now()
+	for _, key := range nodeKeys {
+		values = append(values, "(?, ?, ?, ?)")
+		args = append(args, groupId, key, admin, now)
+	}
+	query := "INSERT INTO group_nodes (group_id, node_id, added_by, created_at) VALUES " + strings.Join(values, ", ") + " ON CONFLICT DO NOTHING"
+	return execBulk(u.db, "adding nodes to group", query, args)
+}
+
+func (u data) removeGroupNode(groupId uint, nodeKey string) error {
+	return execBulk(u.db, "removing node from group", "DELETE FROM group_nodes WHERE group_id = ? AND node_id = ?", []interface{}{groupId, nodeKey})
+}
@@ -0,0 +1,280 @@
+package node_checker
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Rules of eligibility policies, reported with every failure
+const (
+	RuleExcluded      = "excluded"
+	RuleMinPercentage = "min-percentage"
+	RuleMinDaysOnline = "min-days-online"
+	RuleMaxRestarts   = "max-restarts"
+	RuleMinAge        = "min-age"
+)
+
+// Formats of eligibility exports
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatJSON = "json"
+)
+
+// EligibilityPolicy defines rules a node has to satisfy within a period to be eligible for rewards.
+// Rules with zero value are not applied, max restarts is applied whenever it is set.
+type EligibilityPolicy struct {
+	Name          string        `mapstructure:"name" json:"name"`
+	MinPercentage float64       `mapstructure:"min-percentage" json:"minPercentage"`
+	MinDaysOnline int           `mapstructure:"min-days-online" json:"minDaysOnline"`
+	MaxRestarts   *int          `mapstructure:"max-restarts" json:"maxRestarts,omitempty"`
+	MinAge        time.Duration `mapstructure:"min-age" json:"minAge"`
+	ExcludedKeys  []string      `mapstructure:"excluded-keys" json:"excludedKeys"`
+}
+
+// eligibilityPolicies returns policies defined in the configuration
+func eligibilityPolicies() []EligibilityPolicy {
+	var policies []EligibilityPolicy
+	if err := viper.UnmarshalKey("eligibility.policies", &policies); err != nil {
+		log.Errorf("Unable to read eligibility policies from configuration - %v", err)
+	}
+	return policies
+}
+
+func findEligibilityPolicy(name string) (EligibilityPolicy, error) {
+	for _, policy := range eligibilityPolicies() {
+		if policy.Name == name {
+			return policy, nil
+		}
+	}
+	return EligibilityPolicy{}, errUnknownEligibilityPolicy
+}
+
+// check returns every rule of the policy which the node does not satisfy
+func (p EligibilityPolicy) check(node NodeEligibility) []EligibilityFailure {
+	var failures []EligibilityFailure
+	for _, key := range p.ExcludedKeys {
+		if key == node.Key {
+			failures = append(failures, EligibilityFailure{Rule: RuleExcluded, Reason: "node is excluded by the policy"})
+			break
+		}
+	}
+	if p.MinPercentage > 0 && node.Percentage < p.MinPercentage {
+		failures = append(failures, EligibilityFailure{
+			Rule:   RuleMinPercentage,
+			Reason: fmt.Sprintf("uptime %.2f%% is below %.2f%%", node.Percentage, p.MinPercentage),
+		})
+	}
+	if p.MinDaysOnline > 0 && node.DaysOnline < p.MinDaysOnline {
+		failures = append(failures, EligibilityFailure{
+			Rule:   RuleMinDaysOnline,
+			Reason: fmt.Sprintf("online in %d days, at least %d required", node.DaysOnline, p.MinDaysOnline),
+		})
+	}
+	if p.MaxRestarts != nil && node.Restarts > *p.MaxRestarts {
+		failures = append(failures, EligibilityFailure{
+			Rule:   RuleMaxRestarts,
+			Reason: fmt.Sprintf("restarted %d times, at most %d allowed", node.Restarts, *p.MaxRestarts),
+		})
+	}
+	if age := time.Duration(node.Age) * time.Second; p.MinAge > 0 && age < p.MinAge {
+		failures = append(failures, EligibilityFailure{
+			Rule:   RuleMinAge,
+			Reason: fmt.Sprintf("node is known for %v, at least %v required", age, p.MinAge),
+		})
+	}
+	return failures
+}
+
+// evaluateEligibility evaluates the policy against every known node within the [startDate, endDate) period.
+// Days online are counted in the given location.
+func (ns *Service) evaluateEligibility(policy EligibilityPolicy, startDate time.Time, endDate time.Time, location *time.Location) (EligibilityReport, error) {
+	report := EligibilityReport{
+		Policy:     policy,
+		StartDate:  startDate,
+		EndDate:    endDate,
+		Eligible:   []NodeEligibility{},
+		Ineligible: []NodeEligibility{},
+	}
+	nodes, err := ns.db.findNodes()
+	if err != nil {
+		if err == errCannotLoadDataFromDatabase {
+			return report, nil
+		}
+		return report, errCannotFindNodes
+	}
+	restarts, err := ns.db.countNodeEvents(EventRestarted, startDate, endDate)
+	if err != nil {
+		return report, errCannotLoadDataFromDatabase
+	}
+	excused, err := ns.excusedPeriods(startDate, endDate)
+	if err != nil {
+		return report, errCannotLoadDataFromDatabase
+	}
+	adjustments, err := ns.activeAdjustments(startDate, endDate)
+	if err != nil {
+		return report, errCannotLoadDataFromDatabase
+	}
+	boundary, err := ns.compactionBoundary()
+	if err != nil {
+		return report, errCannotLoadDataFromDatabase
+	}
+
+	intervals, err := ns.periodRunningIntervals(nodes, boundary, startDate, endDate)
+	if err != nil {
+		log.Errorf("Unable to read uptimes while evaluating eligibility - %v", err)
+		return report, errCannotLoadData
+	}
+	firstStarts, err := ns.db.findFirstUptimeTimes()
+	if err != nil {
+		return report, errCannotLoadDataFromDatabase
+	}
+
+	for _, node := range nodes {
+		running := intervals[node.Key]
+		uptime := nodeUptime(node, running, excused.forNode(node.Key), adjustments[node.Key], startDate, endDate)
+		result := NodeEligibility{
+			Key:        node.Key,
+			Uptime:     uptime.Uptime,
+			Percentage: uptime.Percentage,
+			Excused:    uptime.Excused,
+			Adjusted:   uptime.Adjusted,
+			DaysOnline: daysOnline(running, startDate, endDate, location),
+			Restarts:   restarts[node.Key],
+			Age:        nodeAge(node, firstStarts[node.Key], running, endDate),
+		}
+		result.Failures = policy.check(result)
+		if len(result.Failures) == 0 {
+			result.Eligible = true
+			report.Eligible = append(report.Eligible, result)
+		} else {
+			report.Ineligible = append(report.Ineligible, result)
+		}
+	}
+	return report, nil
+}
+
+// daysOnline counts days in the location, clipped to the period, in which the node was running at any time
+func daysOnline(running []interval, startDate time.Time, endDate time.Time, location *time.Location) int {
+	days := 0
+	for day := startOfDay(startDate, location); day.Before(endDate); {
+		year, month, date := day.Date()
+		next := time.Date(year, month, date+1, 0, 0, 0, 0, location)
+		from, to := day, next
+		if from.Before(startDate) {
+			from = startDate
+		}
+		if to.After(endDate) {
+			to = endDate
+		}
+		if len(intersectPeriod(running, from, to)) > 0 {
+			days++
+		}
+		day = next
+	}
+	return days
+}
+
+// nodeAge returns seconds since the node was first seen or started, whichever is earlier, until the end of the period.
+// Running intervals reconstructed from rollups may start before the first raw uptime which was kept.
+func nodeAge(node Node, firstStart time.Time, running []interval, endDate time.Time) float64 {
+	firstSeen := node.CreatedAt
+	if !firstStart.IsZero() && firstStart.Before(firstSeen) {
+		firstSeen = firstStart
+	}
+	if len(running) > 0 && running[0].Start.Before(firstSeen) {
+		firstSeen = running[0].Start
+	}
+	if firstSeen.IsZero() || !endDate.After(firstSeen) {
+		return 0
+	}
+	return toFixed(endDate.Sub(firstSeen).Seconds(), 0)
+}
+
+// ExportEligibility evaluates the named policy for the month (YYYY-MM, previous month when empty) cut in the
+// timezone (configured one when empty) and writes the report in the given format
+func ExportEligibility(policyName string, month string, timezone string, format string, w io.Writer) error {
+	policy, err := findEligibilityPolicy(policyName)
+	if err != nil {
+		return err
+	}
+	period, location, err := reportingMonth(month, timezone)
+	if err != nil {
+		return err
+	}
+
+	ns := DefaultService()
+	report, err := ns.evaluateEligibility(policy, period.start(location), period.end(location), location)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case ExportFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case ExportFormatCSV:
+		return writeEligibilityCSV(w, report)
+	default:
+		return errUnknownExportFormat
+	}
+}
+
+func writeEligibilityCSV(w io.Writer, report EligibilityReport) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"key", "eligible", "percentage", "excused_seconds", "adjusted_seconds", "days_online", "restarts", "age_seconds", "reasons"})
+	for _, nodes := range [][]NodeEligibility{report.Eligible, report.Ineligible} {
+		for _, node := range nodes {
+			var reasons []string
+			for _, failure := range node.Failures {
+				reasons = append(reasons, failure.Rule+": "+failure.Reason)
+			}
+			writer.Write([]string{
+				node.Key,
+				strconv.FormatBool(node.Eligible),
+				strconv.FormatFloat(node.Percentage, 'f', 2, 64),
+				strconv.FormatFloat(node.Excused, 'f', 0, 64),
+				strconv.FormatFloat(node.Adjusted, 'f', 0, 64),
+				strconv.Itoa(node.DaysOnline),
+				strconv.Itoa(node.Restarts),
+				strconv.FormatFloat(node.Age, 'f', 0, 64),
+				strings.Join(reasons, "; "),
+			})
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+type EligibilityFailure struct {
+	Rule   string `json:"rule"`
+	Reason string `json:"reason"`
+}
+
+type NodeEligibility struct {
+	Key        string               `json:"key"`
+	Eligible   bool                 `json:"eligible"`
+	Uptime     float64              `json:"uptime"`
+	Percentage float64              `json:"percentage"`
+	Excused    float64              `json:"excused"`
+	Adjusted   float64              `json:"adjusted"`
+	DaysOnline int                  `json:"daysOnline"`
+	Restarts   int                  `json:"restarts"`
+	Age        float64              `json:"age"` // seconds since the node was first seen until the end of the period
+	Failures   []EligibilityFailure `json:"failures,omitempty"`
+}
+
+type EligibilityReport struct {
+	Policy     EligibilityPolicy `json:"policy"`
+	StartDate  time.Time         `json:"startDate"`
+	EndDate    time.Time         `json:"endDate"`
+	Eligible   []NodeEligibility `json:"eligible"`
+	Ineligible []NodeEligibility `json:"ineligible"`
+}
@@ -6,4 +6,60 @@ var errCannotLoadData = errors.New("node checker controller: cannot load data fo
 var errCannotFindNodes = errors.New("node checker controller: cannot find nodes")
 var errCannotFindNodeWithKey = errors.New("node checker controller: cannot find node with key")
 var errCannotLoadDataFromDatabase = errors.New("node checker controller: cannot load data from database")
-var errUnableToProcessRequest = errors.New("node checker controller: cannot process request")
\ No newline at end of file
+var errNoNodeSources = errors.New("node checker controller: no node sources configured")
+var errUnableToProcessRequest = errors.New("node checker controller: cannot process request")
+var errInvalidHeartbeat = errors.New("node checker controller: invalid heartbeat")
+var errInvalidHeartbeatSignature = errors.New("node checker controller: invalid heartbeat signature")
+var errStaleHeartbeat = errors.New("node checker controller: heartbeat timestamp out of allowed range")
+var errReplayedHeartbeat = errors.New("node checker controller: heartbeat nonce already used")
+var errHeartbeatRateLimited = errors.New("node checker controller: heartbeat sent too frequently")
+var errNotLeader = errors.New("node checker controller: another instance is collecting data")
+var errCannotFindCollectionRun = errors.New("node checker controller: cannot find collection run")
+var errInvalidPagination = errors.New("node checker controller: invalid page or page size")
+var errRebuildTargetNotEmpty = errors.New("node checker controller: rebuild target schema already contains nodes")
+var errInvalidMonth = errors.New("node checker controller: invalid month or month range")
+var errInvalidGranularity = errors.New("node checker controller: granularity has to be hour or day")
+var errInvalidSeriesRange = errors.New("node checker controller: invalid or too long range for the granularity")
+var errInvalidTimezone = errors.New("node checker controller: unknown timezone")
+var errUnknownEligibilityPolicy = errors.New("node checker controller: unknown eligibility policy")
+var errUnknownExportFormat = errors.New("node checker controller: unknown export format")
+var errInvalidBudget = errors.New("node checker controller: budget has to be a positive amount of coins with at most 6 decimals")
+var errUnknownPayoutScheme = errors.New("node checker controller: payout scheme has to be equal, uptime-weighted or tiered")
+var errCannotSavePayout = errors.New("node checker controller: cannot save payout run")
+var errCannotFindPayoutRun = errors.New("node checker controller: cannot find payout run")
+var errInvalidReconcileSource = errors.New("node checker controller: reconcile source has to be csv:path, live:YYYY-MM or monthly:YYYY-MM")
+var errInvalidReconcileCSV = errors.New("node checker controller: csv file does not contain requested columns or values")
+var errUnknownReconcileField = errors.New("node checker controller: reconciled field has to be uptime, downtime or percentage")
+var errUnknownSortField = errors.New("node checker controller: unknown sort field")
+var errInvalidResolution = errors.New("node checker controller: resolution has to be a duration of at least one minute")
+var errInvalidName = errors.New("node checker controller: name must not be empty")
+var errInvalidAddress = errors.New("node checker controller: invalid skycoin address")
+var errNameAlreadyUsed = errors.New("node checker controller: name is already used")
+var errCannotFindOwner = errors.New("node checker controller: cannot find owner")
+var errCannotFindGroup = errors.New("node checker controller: cannot find node group")
+var errInvalidMaintenanceWindow = errors.New("node checker controller: maintenance window has to have a reason and end after it starts")
+var errCannotFindMaintenanceWindow = errors.New("node checker controller: cannot find maintenance window")
+var errInvalidAdjustment = errors.New("node checker controller: adjustment has to have a node, a kind, a reason and seconds which fit into its period")
+var errAdjustmentAlreadyReverted = errors.New("node checker controller: adjustment is already reverted")
+var errCannotFindAdjustment = errors.New("node checker controller: cannot find adjustment")
+var errUnauthorized = errors.New("node checker controller: valid admin token is required")
+var errReportChainConflict = errors.New("node checker controller: another report was appended to the chain concurrently")
+var errCannotFindMonthlyReport = errors.New("node checker controller: month has no published report")
+var errCannotFindNodeInReport = errors.New("node checker controller: node is not part of the published report")
+var errCorruptedReport = errors.New("node checker controller: published report does not match its Merkle root")
+var errInvalidSigningKey = errors.New("node checker controller: configured signing key is not a valid secp256k1 secret key")
+var errSigningNotConfigured = errors.New("node checker controller: exports are not signed")
+var errInvalidPublicKey = errors.New("node checker controller: invalid public key")
+var errInvalidExportSignature = errors.New("node checker controller: export signature is not valid")
+var errRetentionDisabled = errors.New("node checker controller: retention of raw uptimes is not configured")
+var errNothingToCompact = errors.New("node checker controller: no raw uptimes to compact")
+var errMonthsNotClosed = errors.New("node checker controller: months before the retention boundary are not closed yet")
+var errSeriesTimezoneUnavailable = errors.New("node checker controller: days in a timezone other than the reporting one require hourly rollups")
+var errSeriesTimezoneUnaligned = errors.New("node checker controller: timezone is not offset from UTC by whole hours")
+var errCompactionUnaligned = errors.New("node checker controller: hourly rollups cross month boundaries of the reporting timezone")
+var errCollectionConflict = errors.New("node checker controller: uptimes were changed by a concurrent collection")
+var errDuplicateReconcileKey = errors.New("node checker controller: csv file contains the same node key more than once")
+var errQuorumNotReached = errors.New("node checker controller: fewer node sources responded than the quorum")
+var errSnapshotsMissing = errors.New("node checker controller: collection runs of the source schema have no archived snapshots")
+var errNoPayoutTiers = errors.New("node checker controller: tiered payout scheme requires configured payout tiers")
+var errNothingToPay = errors.New("node checker controller: no eligible node with a valid address has a positive weight")
\ No newline at end of file
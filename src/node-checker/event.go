@@ -0,0 +1,95 @@
+package node_checker
+
+import (
+	"time"
+)
+
+// Types of node events
+const (
+	EventFirstSeen   = "first_seen"
+	EventCameOnline  = "came_online"
+	EventWentOffline = "went_offline"
+	EventRestarted   = "restarted"
+)
+
+// Granularities of restart counts in node events report
+const (
+	PeriodDay   = "day"
+	PeriodMonth = "month"
+)
+
+// collectionEvents returns events caused by the node being reported in a run
+func collectionEvents(key string, found bool, wasOffline bool, uptime Uptime, isNew bool, currentTime time.Time, runId *uint) []NodeEvent {
+	event := func(eventType string, occurredAt time.Time) NodeEvent {
+		return NodeEvent{
+			NodeId:     key,
+			Type:       eventType,
+			OccurredAt: occurredAt,
+			DetectedAt: currentTime,
+			RunId:      runId,
+		}
+	}
+
+	if !found {
+		return []NodeEvent{event(EventFirstSeen, uptime.CreatedAt)}
+	}
+	var events []NodeEvent
+	if wasOffline {
+		if isNew {
+			events = append(events, event(EventCameOnline, uptime.CreatedAt))
+		} else {
+			events = append(events, event(EventCameOnline, currentTime))
+		}
+	}
+	if isNew {
+		events = append(events, event(EventRestarted, uptime.CreatedAt))
+	}
+	return events
+}
+
+// getNodeEvents returns events of the node, restarts are counted per day or month in the given location
+func (ns *Service) getNodeEvents(nodeKey string, startDate time.Time, endDate time.Time, period string, location *time.Location) (NodeEventsResponse, error) {
+	events, err := ns.db.findNodeEvents(nodeKey, startDate, endDate)
+	if err != nil {
+		return NodeEventsResponse{}, errCannotLoadDataFromDatabase
+	}
+
+	response := NodeEventsResponse{
+		Key:    nodeKey,
+		Events: events,
+	}
+	counts := make(map[time.Time]int)
+	var periods []time.Time
+	for _, event := range events {
+		if event.Type != EventRestarted {
+			continue
+		}
+		periodStart := truncateToPeriod(event.OccurredAt, period, location)
+		if _, found := counts[periodStart]; !found {
+			periods = append(periods, periodStart)
+		}
+		counts[periodStart]++
+	}
+	for _, periodStart := range periods {
+		response.Restarts = append(response.Restarts, PeriodRestarts{Period: periodStart, Restarts: counts[periodStart]})
+	}
+	return response, nil
+}
+
+func truncateToPeriod(t time.Time, period string, location *time.Location) time.Time {
+	if period == PeriodMonth {
+		return monthOf(t, location).start(location)
+	}
+	return startOfDay(t, location)
+}
+
+type PeriodRestarts struct {
+	Period   time.Time `json:"period"`
+	Restarts int       `json:"restarts"`
+}
+
+type NodeEventsResponse struct {
+	Key      string           `json:"key"`
+	Events   []NodeEvent      `json:"events"`
+	Restarts []PeriodRestarts `json:"restarts"`
+}
@@ -0,0 +1,56 @@
+package node_checker
+
+import (
+	"time"
+)
+
+// minFleetResolution is the finest resolution of fleet series, collection runs are not more frequent
+const minFleetResolution = time.Minute
+
+// getFleetSeries returns fleet samples within the period downsampled to buckets of the resolution
+func (ns *Service) getFleetSeries(startDate time.Time, endDate time.Time, resolution time.Duration) ([]FleetSeriesPoint, error) {
+	if resolution < minFleetResolution {
+		return nil, errInvalidResolution
+	}
+	if !endDate.After(startDate) || endDate.Sub(startDate)/resolution > maxSeriesPoints {
+		return nil, errInvalidSeriesRange
+	}
+	buckets, err := ns.db.findFleetSeries(startDate, endDate, resolution)
+	if err != nil {
+		return nil, errCannotLoadDataFromDatabase
+	}
+
+	points := make([]FleetSeriesPoint, 0, len(buckets))
+	for _, bucket := range buckets {
+		points = append(points, FleetSeriesPoint{
+			Start:       bucket.BucketStart.UTC(),
+			Samples:     bucket.Samples,
+			Total:       SeriesStat{Min: bucket.TotalMin, Max: bucket.TotalMax, Avg: toFixed(bucket.TotalAvg, 2)},
+			Online:      SeriesStat{Min: bucket.OnlineMin, Max: bucket.OnlineMax, Avg: toFixed(bucket.OnlineAvg, 2)},
+			Offline:     SeriesStat{Min: bucket.OfflineMin, Max: bucket.OfflineMax, Avg: toFixed(bucket.OfflineAvg, 2)},
+			New:         SeriesStat{Min: bucket.NewMin, Max: bucket.NewMax, Avg: toFixed(bucket.NewAvg, 2)},
+			WentOffline: SeriesStat{Min: bucket.WentOfflineMin, Max: bucket.WentOfflineMax, Avg: toFixed(bucket.WentOfflineAvg, 2)},
+			Restarted:   SeriesStat{Min: bucket.RestartedMin, Max: bucket.RestartedMax, Avg: toFixed(bucket.RestartedAvg, 2)},
+		})
+	}
+	return points, nil
+}
+
+type SeriesStat struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+	Avg float64 `json:"avg"`
+}
+
+// FleetSeriesPoint aggregates fleet samples of collection runs within a bucket. Total, online and offline are
+// numbers of known nodes at the time of the run, the others are changes detected by the run.
+type FleetSeriesPoint struct {
+	Start       time.Time  `json:"start"`
+	Samples     int        `json:"samples"`
+	Total       SeriesStat `json:"total"`
+	Online      SeriesStat `json:"online"`
+	Offline     SeriesStat `json:"offline"`
+	New         SeriesStat `json:"new"`
+	WentOffline SeriesStat `json:"wentOffline"`
+	Restarted   SeriesStat `json:"restarted"`
+}
@@ -0,0 +1,105 @@
+package node_checker
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Policies describing how time in which collector was not observing nodes is treated by the calculators
+const (
+	GapPolicyDowntime = "downtime" // gap time not covered by uptime counts as downtime
+	GapPolicyExcused  = "excused"  // gap time not covered by uptime counts neither as uptime nor as downtime
+)
+
+// Reasons stored with collector gaps
+const (
+	GapReasonFetchFailed   = "fetch-failed"
+	GapReasonCollectorDown = "collector-down"
+)
+
+func gapPolicy() string {
+	if viper.GetString("gaps.policy") == GapPolicyExcused {
+		return GapPolicyExcused
+	}
+	return GapPolicyDowntime
+}
+
+// gapThreshold is the minimal time between two successful runs which is considered as a gap
+func gapThreshold() time.Duration {
+	if threshold := viper.GetDuration("gaps.min-duration"); threshold > 0 {
+		return threshold
+	}
+	return 2 * viper.GetDuration("server.refresh-interval")
+}
+
+// recordFailedRun stores the time since the last successful run as collector gap
+func (ns *Service) recordFailedRun(currentTime time.Time) {
+	lastCheck, err := ns.db.findLastCheck()
+	if err != nil {
+		return
+	}
+	gap := CollectorGap{
+		StartedAt: lastCheck,
+		EndedAt:   currentTime,
+		Reason:    GapReasonFetchFailed,
+	}
+	if err := ns.db.saveCollectorGap(&gap); err != nil {
+		log.Errorf("Unable to record collector gap since %v - %v", lastCheck, err)
+	}
+}
+
+// detectCollectorGap stores the time since the last successful run as collector gap if it is longer than expected
+func (ns *Service) detectCollectorGap(currentTime time.Time) {
+	lastCheck, err := ns.db.findLastCheck()
+	if err != nil {
+		return
+	}
+	if currentTime.Sub(lastCheck) <= gapThreshold() {
+		return
+	}
+	gap := CollectorGap{
+		StartedAt: lastCheck,
+		EndedAt:   currentTime,
+		Reason:    GapReasonCollectorDown,
+	}
+	log.Warnf("Collector was not observing nodes from %v to %v", lastCheck, currentTime)
+	if err := ns.db.saveCollectorGap(&gap); err != nil {
+		log.Errorf("Unable to record collector gap since %v - %v", lastCheck, err)
+	}
+}
+
+// excusableGaps returns gaps within the period when the configured policy excuses them
+func (ns *Service) excusableGaps(startDate time.Time, endDate time.Time) ([]CollectorGap, error) {
+	if gapPolicy() != GapPolicyExcused {
+		return nil, nil
+	}
+	return ns.db.findCollectorGaps(startDate, endDate)
+}
+
+func (ns *Service) getCollectorGaps(startDate time.Time, endDate time.Time) ([]CollectorGap, error) {
+	gaps, err := ns.db.findCollectorGaps(startDate, endDate)
+	if err != nil {
+		return nil, errCannotLoadDataFromDatabase
+	}
+	return gaps, nil
+}
+
+// gapIntervals turns collector gaps into normalized intervals
+func gapIntervals(gaps []CollectorGap) []interval {
+	intervals := make([]interval, 0, len(gaps))
+	for _, gap := range gaps {
+		intervals = append(intervals, interval{Start: gap.StartedAt, End: gap.EndedAt})
+	}
+	return normalizeIntervals(intervals)
+}
+
+// excusedSeconds returns the part of excused intervals within the period which is not covered by running intervals
+func excusedSeconds(gaps []interval, running []interval, startDate time.Time, endDate time.Time) float64 {
+	if len(gaps) == 0 {
+		return 0
+	}
+	excused := subtractIntervals(intersectPeriod(gaps, startDate, endDate), running)
+	return totalDuration(excused).Seconds()
+}
@@ -0,0 +1,171 @@
+package node_checker
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/spf13/viper"
+)
+
+// HeartbeatRequest is pushed by a visor and signed with its node secret key
+type HeartbeatRequest struct {
+	Key       string `json:"key" binding:"required"`
+	StartTime int    `json:"start_time"`
+	Timestamp int64  `json:"timestamp" binding:"required"`
+	Nonce     string `json:"nonce" binding:"required"`
+	Signature string `json:"signature" binding:"required"`
+}
+
+// hash returns hash of the canonical heartbeat message which is signed by the visor
+func (h HeartbeatRequest) hash() cipher.SHA256 {
+	return cipher.SumSHA256([]byte(fmt.Sprintf("%s|%d|%d|%s", h.Key, h.StartTime, h.Timestamp, h.Nonce)))
+}
+
+// verify checks that heartbeat is signed with the secret key belonging to the node key
+func (h HeartbeatRequest) verify() error {
+	pubKey, err := cipher.PubKeyFromHex(h.Key)
+	if err != nil {
+		return errInvalidHeartbeat
+	}
+	sig, err := cipher.SigFromHex(h.Signature)
+	if err != nil {
+		return errInvalidHeartbeat
+	}
+	if err := cipher.VerifySignature(pubKey, sig, h.hash()); err != nil {
+		return errInvalidHeartbeatSignature
+	}
+	return nil
+}
+
+// heartbeatGuard keeps last accepted heartbeat per key in order to reject too frequent heartbeats. Nonces are
+// stored in the database, so that heartbeats replayed against another instance are rejected as well.
+type heartbeatGuard struct {
+	mux       sync.Mutex
+	lastSeen  map[string]time.Time
+	lastPrune time.Time
+}
+
+func newHeartbeatGuard() *heartbeatGuard {
+	return &heartbeatGuard{
+		lastSeen: make(map[string]time.Time),
+	}
+}
+
+func heartbeatMaxSkew() time.Duration {
+	if skew := viper.GetDuration("heartbeat.max-skew"); skew > 0 {
+		return skew
+	}
+	return 2 * time.Minute
+}
+
+func heartbeatMinInterval() time.Duration {
+	if interval := viper.GetDuration("heartbeat.min-interval"); interval > 0 {
+		return interval
+	}
+	return 30 * time.Second
+}
+
+// heartbeatOfflineAfter returns for how long after its last heartbeat a node is not marked offline by collection runs
+func heartbeatOfflineAfter() time.Duration {
+	if after := viper.GetDuration("heartbeat.offline-after"); after > 0 {
+		return after
+	}
+	return 5 * time.Minute
+}
+
+// admit checks freshness and rate limit of the heartbeat and remembers it when accepted
+func (g *heartbeatGuard) admit(h HeartbeatRequest, currentTime time.Time) error {
+	skew := heartbeatMaxSkew()
+	sent := time.Unix(h.Timestamp, 0)
+	if sent.Before(currentTime.Add(-skew)) || sent.After(currentTime.Add(skew)) {
+		return errStaleHeartbeat
+	}
+
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	g.prune(currentTime)
+
+	if last, found := g.lastSeen[h.Key]; found && currentTime.Sub(last) < heartbeatMinInterval() {
+		return errHeartbeatRateLimited
+	}
+	g.lastSeen[h.Key] = currentTime
+	return nil
+}
+
+// prune forgets heartbeats which can no longer limit the next one
+func (g *heartbeatGuard) prune(currentTime time.Time) {
+	minInterval := heartbeatMinInterval()
+	if currentTime.Sub(g.lastPrune) < minInterval {
+		return
+	}
+	g.lastPrune = currentTime
+	for key, last := range g.lastSeen {
+		if currentTime.Sub(last) >= minInterval {
+			delete(g.lastSeen, key)
+		}
+	}
+}
+
+// runningTime returns running time of the node at the current time, as the visor reported it at the signed timestamp
+func (h HeartbeatRequest) runningTime(currentTime time.Time) int {
+	return h.StartTime + int(currentTime.Sub(time.Unix(h.Timestamp, 0))/time.Second)
+}
+
+// staleHeartbeat reports whether the node started before its last uptime by more than the offset used to match
+// uptimes, so the heartbeat was sent before the node restarted and must not extend the new uptime
+func staleHeartbeat(lastUptime Uptime, found bool, def NodeDef, currentTime time.Time) bool {
+	if !found {
+		return false
+	}
+	started := currentTime.Add(time.Duration(-def.StartTime) * time.Second)
+	return started.Before(lastUptime.CreatedAt.Add(time.Duration(-UptimeDifferenceOffsetInSeconds) * time.Second))
+}
+
+// receiveHeartbeat verifies heartbeat pushed by a visor and records its uptime the same way as collected nodes
+func (ns *Service) receiveHeartbeat(h HeartbeatRequest) error {
+	if err := h.verify(); err != nil {
+		log.Warnf("Rejected heartbeat for node %v - %v", h.Key, err)
+		return err
+	}
+	currentTime := time.Now()
+	if err := ns.heartbeats.admit(h, currentTime); err != nil {
+		log.Debugf("Rejected heartbeat for node %v - %v", h.Key, err)
+		return err
+	}
+
+	if err := ns.db.useHeartbeatNonce(h.Key, h.Nonce, time.Unix(h.Timestamp, 0), currentTime.Add(-heartbeatMaxSkew())); err != nil {
+		if err == errReplayedHeartbeat {
+			log.Debugf("Rejected heartbeat for node %v - %v", h.Key, err)
+			return err
+		}
+		log.Errorf("Unable to record heartbeat nonce of node %v - %v", h.Key, err)
+		return errUnableToProcessRequest
+	}
+
+	uptimeThreshold := int(viper.GetDuration("server.uptime-threshold").Seconds())
+	startTime := h.runningTime(currentTime)
+	if startTime <= uptimeThreshold {
+		return nil
+	}
+
+	res := NodeResponse{{Key: h.Key, StartTime: startTime}}
+	run := CollectionRun{StartedAt: currentTime}
+	if err := ns.persistNodes(res, currentTime, &run, true); err != nil {
+		log.Errorf("Unable to record heartbeat of node %v - %v", h.Key, err)
+		return errUnableToProcessRequest
+	}
+	if archive := ns.sources.archive; archive != nil {
+		payload, err := json.Marshal(res)
+		if err == nil {
+			err = archive.save(snapshotHeartbeat, currentTime, payload)
+		}
+		if err != nil {
+			log.Errorf("Unable to archive heartbeat snapshot of node %v - %v", h.Key, err)
+		}
+	}
+	return nil
+}
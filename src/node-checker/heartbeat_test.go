@@ -0,0 +1,49 @@
+package node_checker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHeartbeatRunningTime(t *testing.T) {
+	currentTime := time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		sent     time.Time
+		expected int
+	}{
+		{name: "received when sent", sent: currentTime, expected: 3600},
+		{name: "delayed", sent: currentTime.Add(-90 * time.Second), expected: 3690},
+		{name: "sent by a clock ahead", sent: currentTime.Add(30 * time.Second), expected: 3570},
+	}
+	for _, test := range tests {
+		h := HeartbeatRequest{StartTime: 3600, Timestamp: test.sent.Unix()}
+		if actual := h.runningTime(currentTime); actual != test.expected {
+			t.Errorf("%v: expected running time %v, got %v", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestStaleHeartbeat(t *testing.T) {
+	currentTime := time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC)
+	offset := time.Duration(UptimeDifferenceOffsetInSeconds) * time.Second
+	last := Uptime{NodeId: "a", StartTime: 600, CreatedAt: currentTime.Add(-time.Hour)}
+	tests := []struct {
+		name     string
+		started  time.Time
+		found    bool
+		expected bool
+	}{
+		{name: "unknown node", started: currentTime.Add(-2 * time.Hour), expected: false},
+		{name: "same run", started: last.CreatedAt, found: true, expected: false},
+		{name: "same run within offset", started: last.CreatedAt.Add(-offset), found: true, expected: false},
+		{name: "restarted since", started: currentTime.Add(-time.Minute), found: true, expected: false},
+		{name: "previous run", started: last.CreatedAt.Add(-offset - time.Second), found: true, expected: true},
+	}
+	for _, test := range tests {
+		def := NodeDef{Key: "a", StartTime: int(currentTime.Sub(test.started) / time.Second)}
+		if actual := staleHeartbeat(last, test.found, def, currentTime); actual != test.expected {
+			t.Errorf("%v: expected stale %v, got %v", test.name, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package node_checker
+
+import (
+	"sort"
+	"time"
+)
+
+// interval is a half-open [Start, End) time range
+type interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (i interval) duration() time.Duration {
+	return i.End.Sub(i.Start)
+}
+
+// uptimeIntervals turns uptime rows into normalized intervals in which the node was running.
+// Each uptime covers the time from the node start until it was seen running for the last time.
+func uptimeIntervals(uptimes []Uptime) []interval {
+	intervals := make([]interval, 0, len(uptimes))
+	for _, uptime := range uptimes {
+		intervals = append(intervals, interval{
+			Start: uptime.CreatedAt,
+			End:   uptime.CreatedAt.Add(time.Duration(uptime.StartTime) * time.Second),
+		})
+	}
+	return normalizeIntervals(intervals)
+}
+
+// normalizeIntervals drops empty intervals and merges overlapping and adjacent ones into a sorted list
+func normalizeIntervals(intervals []interval) []interval {
+	var sorted []interval
+	for _, i := range intervals {
+		if i.End.After(i.Start) {
+			sorted = append(sorted, i)
+		}
+	}
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a].Start.Before(sorted[b].Start) })
+
+	var merged []interval
+	for _, i := range sorted {
+		last := len(merged) - 1
+		if last >= 0 && !i.Start.After(merged[last].End) {
+			if i.End.After(merged[last].End) {
+				merged[last].End = i.End
+			}
+			continue
+		}
+		merged = append(merged, i)
+	}
+	return merged
+}
+
+// intersectPeriod clips normalized intervals to the [start, end) period
+func intersectPeriod(intervals []interval, start time.Time, end time.Time) []interval {
+	var result []interval
+	if !end.After(start) {
+		return result
+	}
+	for _, i := range intervals {
+		if !i.End.After(start) {
+			continue
+		}
+		if !i.Start.Before(end) {
+			break
+		}
+		if i.Start.Before(start) {
+			i.Start = start
+		}
+		if i.End.After(end) {
+			i.End = end
+		}
+		result = append(result, i)
+	}
+	return result
+}
+
+// subtractIntervals returns parts of normalized intervals a which are not covered by normalized intervals b
+func subtractIntervals(a []interval, b []interval) []interval {
+	var result []interval
+	j := 0
+	for _, i := range a {
+		start := i.Start
+		for j < len(b) && !b[j].End.After(start) {
+			j++
+		}
+		for k := j; k < len(b) && b[k].Start.Before(i.End); k++ {
+			if b[k].Start.After(start) {
+				result = append(result, interval{Start: start, End: b[k].Start})
+			}
+			if b[k].End.After(start) {
+				start = b[k].End
+			}
+		}
+		if start.Before(i.End) {
+			result = append(result, interval{Start: start, End: i.End})
+		}
+	}
+	return result
+}
+
+// totalDuration sums durations of normalized intervals
+func totalDuration(intervals []interval) time.Duration {
+	var total time.Duration
+	for _, i := range intervals {
+		total += i.duration()
+	}
+	return total
+}
@@ -0,0 +1,295 @@
+package node_checker
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+var intervalBase = time.Date(2026, time.January, 31, 20, 0, 0, 0, time.UTC)
+
+// hours returns the interval between the given hour offsets from intervalBase
+func hours(start, end float64) interval {
+	return interval{
+		Start: intervalBase.Add(time.Duration(start * float64(time.Hour))),
+		End:   intervalBase.Add(time.Duration(end * float64(time.Hour))),
+	}
+}
+
+func at(hour float64) time.Time {
+	return intervalBase.Add(time.Duration(hour * float64(time.Hour)))
+}
+
+func TestNormalizeIntervals(t *testing.T) {
+	tests := []struct {
+		name      string
+		intervals []interval
+		expected  []interval
+	}{
+		{name: "empty", intervals: nil, expected: nil},
+		{name: "single", intervals: []interval{hours(0, 1)}, expected: []interval{hours(0, 1)}},
+		{name: "zero length dropped", intervals: []interval{hours(1, 1)}, expected: nil},
+		{name: "inverted dropped", intervals: []interval{hours(2, 1), hours(3, 4)}, expected: []interval{hours(3, 4)}},
+		{name: "unsorted", intervals: []interval{hours(5, 6), hours(0, 1), hours(2, 3)}, expected: []interval{hours(0, 1), hours(2, 3), hours(5, 6)}},
+		{name: "overlapping", intervals: []interval{hours(0, 2), hours(1, 3)}, expected: []interval{hours(0, 3)}},
+		{name: "adjacent", intervals: []interval{hours(0, 1), hours(1, 2)}, expected: []interval{hours(0, 2)}},
+		{name: "contained", intervals: []interval{hours(0, 5), hours(1, 2), hours(3, 4)}, expected: []interval{hours(0, 5)}},
+		{name: "gap kept", intervals: []interval{hours(0, 1), hours(1.5, 2)}, expected: []interval{hours(0, 1), hours(1.5, 2)}},
+		{name: "chain", intervals: []interval{hours(3, 5), hours(0, 2), hours(2, 3), hours(7, 8), hours(4, 6)}, expected: []interval{hours(0, 6), hours(7, 8)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := normalizeIntervals(test.intervals); !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestIntersectPeriod(t *testing.T) {
+	tests := []struct {
+		name      string
+		intervals []interval
+		start     time.Time
+		end       time.Time
+		expected  []interval
+	}{
+		{name: "inside", intervals: []interval{hours(1, 2)}, start: at(0), end: at(3), expected: []interval{hours(1, 2)}},
+		{name: "clipped start", intervals: []interval{hours(0, 2)}, start: at(1), end: at(3), expected: []interval{hours(1, 2)}},
+		{name: "clipped end", intervals: []interval{hours(1, 4)}, start: at(0), end: at(3), expected: []interval{hours(1, 3)}},
+		{name: "covering", intervals: []interval{hours(0, 10)}, start: at(2), end: at(3), expected: []interval{hours(2, 3)}},
+		{name: "ends at start", intervals: []interval{hours(0, 1)}, start: at(1), end: at(2), expected: nil},
+		{name: "starts at end", intervals: []interval{hours(2, 3)}, start: at(1), end: at(2), expected: nil},
+		{name: "before and after", intervals: []interval{hours(0, 1), hours(1.5, 2.5), hours(4, 5)}, start: at(2), end: at(3), expected: []interval{hours(2, 2.5)}},
+		{name: "empty period", intervals: []interval{hours(0, 5)}, start: at(2), end: at(2), expected: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := intersectPeriod(test.intervals, test.start, test.end); !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSubtractIntervals(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        []interval
+		b        []interval
+		expected []interval
+	}{
+		{name: "nothing subtracted", a: []interval{hours(0, 2)}, b: nil, expected: []interval{hours(0, 2)}},
+		{name: "hole", a: []interval{hours(0, 4)}, b: []interval{hours(1, 2)}, expected: []interval{hours(0, 1), hours(2, 4)}},
+		{name: "covered", a: []interval{hours(1, 2)}, b: []interval{hours(0, 3)}, expected: nil},
+		{name: "overlapping both ends", a: []interval{hours(1, 3), hours(4, 6)}, b: []interval{hours(2, 5)}, expected: []interval{hours(1, 2), hours(5, 6)}},
+		{name: "disjoint", a: []interval{hours(0, 1)}, b: []interval{hours(2, 3)}, expected: []interval{hours(0, 1)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := subtractIntervals(test.a, test.b); !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestUptimeIntervals(t *testing.T) {
+	uptimes := []Uptime{
+		{CreatedAt: at(2), StartTime: 3600},
+		{CreatedAt: at(0), StartTime: 7200},
+		{CreatedAt: at(5), StartTime: 0},
+	}
+	expected := []interval{hours(0, 3)}
+	if actual := uptimeIntervals(uptimes); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestNodeUptimeMonthBoundaries(t *testing.T) {
+	january := YearMonth{Year: 2026, Month: time.January}
+	february := january.next()
+	node := Node{Key: "node"}
+	tests := []struct {
+		name       string
+		running    []interval
+		excusable  []interval
+		month      YearMonth
+		uptime     float64
+		downtime   float64
+		excused    float64
+		percentage float64
+	}{
+		{
+			name:     "running across the boundary counted in january",
+			running:  []interval{hours(0, 8)},
+			month:    january,
+			uptime:   4 * 3600,
+			downtime: float64(31*24*3600 - 4*3600),
+		},
+		{
+			name:     "running across the boundary counted in february",
+			running:  []interval{hours(0, 8)},
+			month:    february,
+			uptime:   4 * 3600,
+			downtime: float64(28*24*3600 - 4*3600),
+		},
+		{
+			name:       "running the whole month",
+			running:    []interval{{Start: january.start(time.UTC).Add(-time.Hour), End: february.end(time.UTC).Add(time.Hour)}},
+			month:      february,
+			uptime:     28 * 24 * 3600,
+			percentage: 100,
+		},
+		{
+			name:     "overlapping uptimes are not counted twice",
+			running:  normalizeIntervals([]interval{hours(4, 6), hours(5, 7)}),
+			month:    february,
+			uptime:   3 * 3600,
+			downtime: float64(28*24*3600 - 3*3600),
+		},
+		{
+			name:      "gaps excused only while not running",
+			running:   []interval{hours(4, 6)},
+			excusable: []interval{hours(5, 8)},
+			month:     february,
+			uptime:    2 * 3600,
+			excused:   2 * 3600,
+			downtime:  float64(28*24*3600 - 4*3600),
+		},
+		{
+			name:     "no uptime",
+			month:    february,
+			downtime: 28 * 24 * 3600,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start, end := test.month.start(time.UTC), test.month.end(time.UTC)
+			actual := nodeUptime(node, test.running, test.excusable, nil, start, end)
+			if actual.Uptime != test.uptime || actual.Downtime != test.downtime || actual.Excused != test.excused {
+				t.Errorf("expected uptime %v, downtime %v, excused %v, got %v, %v, %v",
+					test.uptime, test.downtime, test.excused, actual.Uptime, actual.Downtime, actual.Excused)
+			}
+			if test.percentage != 0 && actual.Percentage != test.percentage {
+				t.Errorf("expected percentage %v, got %v", test.percentage, actual.Percentage)
+			}
+			if actual.Uptime+actual.Downtime+actual.Excused != end.Sub(start).Seconds() {
+				t.Errorf("uptime, downtime and excused time do not add up to the month")
+			}
+		})
+	}
+}
+
+func TestNodeUptimeAdjustmentsClamped(t *testing.T) {
+	month := YearMonth{Year: 2026, Month: time.February}
+	start, end := month.start(time.UTC), month.end(time.UTC)
+	credit := UptimeAdjustment{Kind: AdjustmentCredit, Seconds: 10 * 3600, StartedAt: start, EndedAt: end}
+	debit := UptimeAdjustment{Kind: AdjustmentDebit, Seconds: 10 * 3600, StartedAt: start, EndedAt: end}
+
+	full := nodeUptime(Node{}, []interval{{Start: start, End: end}}, nil, []UptimeAdjustment{credit}, start, end)
+	if full.Uptime != end.Sub(start).Seconds() || full.Adjusted != 0 {
+		t.Errorf("credit exceeded the month, uptime %v, adjusted %v", full.Uptime, full.Adjusted)
+	}
+	none := nodeUptime(Node{}, []interval{hours(4, 5)}, nil, []UptimeAdjustment{debit}, start, end)
+	if none.Uptime != 0 || none.Adjusted != -3600 {
+		t.Errorf("debit went below zero, uptime %v, adjusted %v", none.Uptime, none.Adjusted)
+	}
+}
+
+// randomIntervals is a list of minute aligned intervals around the month boundary, possibly empty, inverted or overlapping
+type randomIntervals []interval
+
+func (randomIntervals) Generate(rand *rand.Rand, size int) reflect.Value {
+	intervals := make(randomIntervals, rand.Intn(size+1))
+	for i := range intervals {
+		start := intervalBase.Add(time.Duration(rand.Intn(72*60)) * time.Minute)
+		intervals[i] = interval{Start: start, End: start.Add(time.Duration(rand.Intn(12*60)-60) * time.Minute)}
+	}
+	return reflect.ValueOf(intervals)
+}
+
+var quickConfig = &quick.Config{MaxCount: 500, Rand: rand.New(rand.NewSource(1))}
+
+func covered(intervals []interval, t time.Time) bool {
+	for _, i := range intervals {
+		if !t.Before(i.Start) && t.Before(i.End) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestNormalizeIntervalsProperties(t *testing.T) {
+	property := func(intervals randomIntervals) bool {
+		normalized := normalizeIntervals(intervals)
+		var sum time.Duration
+		for _, i := range intervals {
+			if i.End.After(i.Start) {
+				sum += i.duration()
+			}
+		}
+		if totalDuration(normalized) > sum {
+			return false
+		}
+		for k, i := range normalized {
+			if !i.End.After(i.Start) || (k > 0 && !i.Start.After(normalized[k-1].End)) {
+				return false
+			}
+		}
+		if !reflect.DeepEqual(normalizeIntervals(normalized), normalized) {
+			return false
+		}
+		for _, i := range intervals {
+			for _, t := range []time.Time{i.Start, i.End.Add(-time.Nanosecond)} {
+				if i.End.After(i.Start) && !covered(normalized, t) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIntersectPeriodProperties(t *testing.T) {
+	property := func(intervals randomIntervals, from uint16, length uint16) bool {
+		normalized := normalizeIntervals(intervals)
+		start := intervalBase.Add(time.Duration(from) * time.Minute)
+		end := start.Add(time.Duration(length) * time.Minute)
+		clipped := intersectPeriod(normalized, start, end)
+		if totalDuration(clipped) > end.Sub(start) || totalDuration(clipped) > totalDuration(normalized) {
+			return false
+		}
+		for _, i := range clipped {
+			if i.Start.Before(start) || i.End.After(end) || !i.End.After(i.Start) {
+				return false
+			}
+		}
+		// splitting the period at any point splits the uptime without losing or adding any
+		middle := start.Add(end.Sub(start) / 2)
+		split := totalDuration(intersectPeriod(normalized, start, middle)) + totalDuration(intersectPeriod(normalized, middle, end))
+		return split == totalDuration(clipped)
+	}
+	if err := quick.Check(property, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNodeUptimeConsecutiveMonthsProperties(t *testing.T) {
+	january := YearMonth{Year: 2026, Month: time.January}
+	property := func(intervals randomIntervals) bool {
+		running := normalizeIntervals(intervals)
+		first := nodeUptime(Node{}, running, nil, nil, january.start(time.UTC), january.end(time.UTC))
+		second := nodeUptime(Node{}, running, nil, nil, january.next().start(time.UTC), january.next().end(time.UTC))
+		both := nodeUptime(Node{}, running, nil, nil, january.start(time.UTC), january.next().end(time.UTC))
+		return first.Uptime+second.Uptime == both.Uptime && first.Percentage <= 100 && second.Percentage <= 100
+	}
+	if err := quick.Check(property, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
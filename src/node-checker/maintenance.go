@@ -0,0 +1,160 @@
+package node_checker
+
+import (
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Policies describing how maintenance windows are treated by the calculators
+const (
+	MaintenancePolicyExcused = "excused" // window time not covered by uptime counts neither as uptime nor as downtime
+	MaintenancePolicyIgnored = "ignored" // windows are only informative, their time not covered by uptime counts as downtime
+)
+
+func maintenancePolicy() string {
+	if viper.GetString("maintenance.policy") == MaintenancePolicyIgnored {
+		return MaintenancePolicyIgnored
+	}
+	return MaintenancePolicyExcused
+}
+
+// excusableWindows returns maintenance windows within the period when the configured policy excuses them
+func (ns *Service) excusableWindows(startDate time.Time, endDate time.Time) ([]MaintenanceWindow, error) {
+	if maintenancePolicy() != MaintenancePolicyExcused {
+		return nil, nil
+	}
+	return ns.db.findMaintenanceWindows(startDate, endDate, "")
+}
+
+// excusedPeriods holds normalized intervals excused for every node and intervals excused only for single nodes
+type excusedPeriods struct {
+	global []interval
+	nodes  map[string][]interval
+}
+
+// forNode returns normalized intervals excused for the node
+func (e excusedPeriods) forNode(nodeKey string) []interval {
+	own := e.nodes[nodeKey]
+	if len(own) == 0 {
+		return e.global
+	}
+	return normalizeIntervals(append(append([]interval{}, e.global...), own...))
+}
+
+// excusedPeriods returns collector gaps and maintenance windows within the period which are excused by the configured policies
+func (ns *Service) excusedPeriods(startDate time.Time, endDate time.Time) (excusedPeriods, error) {
+	gaps, err := ns.excusableGaps(startDate, endDate)
+	if err != nil {
+		return excusedPeriods{}, err
+	}
+	windows, err := ns.excusableWindows(startDate, endDate)
+	if err != nil {
+		return excusedPeriods{}, err
+	}
+	global := gapIntervals(gaps)
+	nodes := make(map[string][]interval)
+	for _, window := range windows {
+		i := interval{Start: window.StartedAt, End: window.EndedAt}
+		if window.NodeKey == nil {
+			global = append(global, i)
+		} else {
+			nodes[*window.NodeKey] = append(nodes[*window.NodeKey], i)
+		}
+	}
+	for key, intervals := range nodes {
+		nodes[key] = normalizeIntervals(intervals)
+	}
+	return excusedPeriods{global: normalizeIntervals(global), nodes: nodes}, nil
+}
+
+// maintenanceWindow validates the request and fills the window from it
+func maintenanceWindow(window *MaintenanceWindow, request MaintenanceWindowRequest) error {
+	reason := strings.TrimSpace(request.Reason)
+	if reason == "" || request.StartedAt.IsZero() || !request.EndedAt.After(request.StartedAt) {
+		return errInvalidMaintenanceWindow
+	}
+	window.NodeKey = nil
+	if key := strings.TrimSpace(request.NodeKey); key != "" {
+		window.NodeKey = &key
+	}
+	window.StartedAt = request.StartedAt
+	window.EndedAt = request.EndedAt
+	window.Reason = reason
+	return nil
+}
+
+// warnClosedMonths warns when a change starting at the time affects months whose monthly uptimes were already created
+func warnClosedMonths(subject string, id uint, startedAt time.Time) {
+	location := reportingLocation()
+	if month := monthOf(startedAt, location); month.end(location).Before(time.Now()) {
+		log.Warnf("%v %v changes closed months since %v, monthly uptimes have to be recomputed", subject, id, month)
+	}
+}
+
+func (ns *Service) createMaintenanceWindow(request MaintenanceWindowRequest, admin string) (MaintenanceWindow, error) {
+	window := MaintenanceWindow{CreatedBy: admin, UpdatedBy: admin}
+	if err := maintenanceWindow(&window, request); err != nil {
+		return MaintenanceWindow{}, err
+	}
+	if err := ns.db.saveMaintenanceWindow(&window); err != nil {
+		return MaintenanceWindow{}, err
+	}
+	log.Infof("Maintenance window %v from %v to %v declared by %v", window.Id, window.StartedAt, window.EndedAt, admin)
+	warnClosedMonths("Maintenance window", window.Id, window.StartedAt)
+	return window, nil
+}
+
+func (ns *Service) updateMaintenanceWindow(id uint, request MaintenanceWindowRequest, admin string) (MaintenanceWindow, error) {
+	window, err := ns.db.findMaintenanceWindow(id)
+	if err != nil {
+		return MaintenanceWindow{}, err
+	}
+	previous := window
+	if err := maintenanceWindow(&window, request); err != nil {
+		return MaintenanceWindow{}, err
+	}
+	window.UpdatedBy = admin
+	if err := ns.db.saveMaintenanceWindow(&window); err != nil {
+		return MaintenanceWindow{}, err
+	}
+	log.Infof("Maintenance window %v changed from %v - %v to %v - %v by %v", window.Id, previous.StartedAt, previous.EndedAt, window.StartedAt, window.EndedAt, admin)
+	warnClosedMonths("Maintenance window", previous.Id, previous.StartedAt)
+	warnClosedMonths("Maintenance window", window.Id, window.StartedAt)
+	return window, nil
+}
+
+func (ns *Service) deleteMaintenanceWindow(id uint, admin string) error {
+	window, err := ns.db.findMaintenanceWindow(id)
+	if err != nil {
+		return err
+	}
+	if err := ns.db.deleteMaintenanceWindow(id); err != nil {
+		return err
+	}
+	log.Infof("Maintenance window %v from %v to %v created by %v deleted by %v", window.Id, window.StartedAt, window.EndedAt, window.CreatedBy, admin)
+	warnClosedMonths("Maintenance window", window.Id, window.StartedAt)
+	return nil
+}
+
+func (ns *Service) getMaintenanceWindow(id uint) (MaintenanceWindow, error) {
+	return ns.db.findMaintenanceWindow(id)
+}
+
+// getMaintenanceWindows returns windows overlapping the period, only global ones and those of the node when node key is set
+func (ns *Service) getMaintenanceWindows(startDate time.Time, endDate time.Time, nodeKey string) ([]MaintenanceWindow, error) {
+	windows, err := ns.db.findMaintenanceWindows(startDate, endDate, strings.TrimSpace(nodeKey))
+	if err != nil {
+		return nil, errCannotLoadDataFromDatabase
+	}
+	return windows, nil
+}
+
+type MaintenanceWindowRequest struct {
+	NodeKey   string    `json:"nodeKey"` // window applies to every node when empty
+	StartedAt time.Time `json:"startedAt" binding:"required"`
+	EndedAt   time.Time `json:"endedAt" binding:"required"`
+	Reason    string    `json:"reason" binding:"required"`
+}
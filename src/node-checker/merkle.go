@@ -0,0 +1,160 @@
+package node_checker
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// MerkleLeaf is the leaf of a node in the Merkle tree of a published monthly report. Its hash is SHA-256 of its
+// compact JSON serialization and leaves are ordered by node key.
+type MerkleLeaf struct {
+	Key        string `json:"key"`
+	Uptime     int    `json:"uptime"`
+	Percentage string `json:"percentage"`
+}
+
+func (l MerkleLeaf) hash() cipher.SHA256 {
+	// marshalling a struct of strings and numbers does not fail
+	content, _ := json.Marshal(l)
+	return cipher.SumSHA256(content)
+}
+
+// reportLeaves returns leaves of every node of the report ordered by node key
+func reportLeaves(report PublishedReport) []MerkleLeaf {
+	leaves := make([]MerkleLeaf, 0, len(report.Nodes))
+	for _, node := range report.Nodes {
+		leaves = append(leaves, MerkleLeaf{Key: node.Key, Uptime: node.Uptime, Percentage: node.Percentage})
+	}
+	sort.Slice(leaves, func(i, j int) bool { return leaves[i].Key < leaves[j].Key })
+	return leaves
+}
+
+func leafHashes(leaves []MerkleLeaf) []cipher.SHA256 {
+	hashes := make([]cipher.SHA256, 0, len(leaves))
+	for _, leaf := range leaves {
+		hashes = append(hashes, leaf.hash())
+	}
+	return hashes
+}
+
+// merkleRoot computes the root the same way as skycoin cipher.Merkle, leaves are padded by zero hashes
+// to the next power of two and every parent is the hash of its concatenated children
+func merkleRoot(leaves []MerkleLeaf) cipher.SHA256 {
+	return cipher.Merkle(leafHashes(leaves))
+}
+
+// merkleProof returns sibling hashes on the path from the leaf at the index up to the root
+func merkleProof(hashes []cipher.SHA256, index int) []cipher.SHA256 {
+	size := 1
+	for size < len(hashes) {
+		size *= 2
+	}
+	level := make([]cipher.SHA256, size)
+	copy(level, hashes)
+
+	var siblings []cipher.SHA256
+	for len(level) > 1 {
+		siblings = append(siblings, level[index^1])
+		parents := make([]cipher.SHA256, len(level)/2)
+		for i := range parents {
+			parents[i] = cipher.AddSHA256(level[2*i], level[2*i+1])
+		}
+		level = parents
+		index /= 2
+	}
+	return siblings
+}
+
+// publishedContent parses canonical content of the published report
+func publishedContent(report MonthlyReport) (PublishedReport, error) {
+	var content PublishedReport
+	if err := json.Unmarshal([]byte(report.Report), &content); err != nil {
+		return PublishedReport{}, errCorruptedReport
+	}
+	return content, nil
+}
+
+// getMerkleProof returns the inclusion proof of the node in the latest revision of the published report of the month
+func (ns *Service) getMerkleProof(period string, nodeKey string) (MerkleProof, error) {
+	month, err := parseYearMonth(period)
+	if err != nil {
+		return MerkleProof{}, err
+	}
+	report, err := ns.db.findMonthlyReport(month.Year, int(month.Month))
+	if err != nil {
+		return MerkleProof{}, err
+	}
+	content, err := publishedContent(report)
+	if err != nil {
+		return MerkleProof{}, err
+	}
+	leaves := reportLeaves(content)
+	index := sort.Search(len(leaves), func(i int) bool { return leaves[i].Key >= nodeKey })
+	if index == len(leaves) || leaves[index].Key != nodeKey {
+		return MerkleProof{}, errCannotFindNodeInReport
+	}
+
+	hashes := leafHashes(leaves)
+	root := cipher.Merkle(append([]cipher.SHA256{}, hashes...))
+	// reports published before roots were stored have an empty root
+	if report.MerkleRoot != "" && report.MerkleRoot != root.Hex() {
+		return MerkleProof{}, errCorruptedReport
+	}
+	proof := MerkleProof{
+		Month:       month.String(),
+		Revision:    report.Revision,
+		PublishedAt: report.PublishedAt,
+		Root:        root.Hex(),
+		ReportHash:  report.ReportHash,
+		ChainHash:   report.Hash,
+		Leaf:        leaves[index],
+		LeafHash:    hashes[index].Hex(),
+		Index:       index,
+		Leaves:      len(leaves),
+		Siblings:    []string{},
+	}
+	for _, sibling := range merkleProof(hashes, index) {
+		proof.Siblings = append(proof.Siblings, sibling.Hex())
+	}
+	return proof, nil
+}
+
+// VerifyMerkleProof recomputes the root from the leaf and its siblings and compares it with the root of the proof.
+// On every level the current hash is the left child when the index bit of that level is zero.
+func VerifyMerkleProof(proof MerkleProof) bool {
+	hash := proof.Leaf.hash()
+	if hash.Hex() != proof.LeafHash {
+		return false
+	}
+	index := proof.Index
+	for _, value := range proof.Siblings {
+		sibling, err := cipher.SHA256FromHex(value)
+		if err != nil {
+			return false
+		}
+		if index%2 == 0 {
+			hash = cipher.AddSHA256(hash, sibling)
+		} else {
+			hash = cipher.AddSHA256(sibling, hash)
+		}
+		index /= 2
+	}
+	return hash.Hex() == proof.Root
+}
+
+type MerkleProof struct {
+	Month       string     `json:"month"`
+	Revision    int        `json:"revision"`
+	PublishedAt time.Time  `json:"publishedAt"`
+	Root        string     `json:"root"`
+	ReportHash  string     `json:"reportHash"` // hash of the canonical report, linked in the report chain
+	ChainHash   string     `json:"chainHash"`
+	Leaf        MerkleLeaf `json:"leaf"`
+	LeafHash    string     `json:"leafHash"`
+	Index       int        `json:"index"` // zero based position of the leaf
+	Leaves      int        `json:"leaves"`
+	Siblings    []string   `json:"siblings"` // sibling hashes from the leaf level up to the root
+}
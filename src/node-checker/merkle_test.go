@@ -0,0 +1,69 @@
+package node_checker
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+func testLeaves(count int) []MerkleLeaf {
+	leaves := make([]MerkleLeaf, count)
+	for i := range leaves {
+		leaves[i] = MerkleLeaf{Key: fmt.Sprintf("node-%02d", i), Uptime: 86400 * (i + 1), Percentage: fmt.Sprintf("%d.00", 90+i)}
+	}
+	return leaves
+}
+
+func testProof(leaves []MerkleLeaf, index int) MerkleProof {
+	hashes := leafHashes(leaves)
+	proof := MerkleProof{
+		Root:     merkleRoot(leaves).Hex(),
+		Leaf:     leaves[index],
+		LeafHash: hashes[index].Hex(),
+		Index:    index,
+		Leaves:   len(leaves),
+	}
+	for _, sibling := range merkleProof(hashes, index) {
+		proof.Siblings = append(proof.Siblings, sibling.Hex())
+	}
+	return proof
+}
+
+func TestMerkleProof(t *testing.T) {
+	for _, count := range []int{1, 2, 3, 5, 8} {
+		t.Run(fmt.Sprintf("leaves=%d", count), func(t *testing.T) {
+			leaves := testLeaves(count)
+			if root, expected := merkleRoot(leaves), cipher.Merkle(leafHashes(leaves)); root != expected {
+				t.Fatalf("expected root %v, got %v", expected.Hex(), root.Hex())
+			}
+			for index := range leaves {
+				proof := testProof(leaves, index)
+				if !VerifyMerkleProof(proof) {
+					t.Errorf("proof of leaf %v does not verify", index)
+				}
+
+				tampered := proof
+				tampered.Leaf.Uptime++
+				if VerifyMerkleProof(tampered) {
+					t.Errorf("proof of tampered leaf %v verifies", index)
+				}
+				tampered.LeafHash = tampered.Leaf.hash().Hex()
+				if VerifyMerkleProof(tampered) {
+					t.Errorf("proof of tampered leaf %v with its hash verifies", index)
+				}
+
+				for level := range proof.Siblings {
+					tampered := proof
+					tampered.Siblings = append([]string{}, proof.Siblings...)
+					sibling := cipher.MustSHA256FromHex(tampered.Siblings[level])
+					sibling[0] ^= 0xff
+					tampered.Siblings[level] = sibling.Hex()
+					if VerifyMerkleProof(tampered) {
+						t.Errorf("proof of leaf %v with tampered sibling %v verifies", index, level)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package node_checker
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// UnmarshalJSON decodes key and start_time and keeps every other attribute reported by discovery as metadata
+func (d *NodeDef) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	var known struct {
+		Key       string `json:"key"`
+		StartTime int    `json:"start_time"`
+	}
+	if err := json.Unmarshal(data, &known); err != nil {
+		return err
+	}
+	d.Key = known.Key
+	d.StartTime = known.StartTime
+	d.Metadata = nil
+
+	delete(fields, "key")
+	delete(fields, "start_time")
+	if len(fields) == 0 {
+		return nil
+	}
+	d.Metadata = make(map[string]string, len(fields))
+	for attribute, raw := range fields {
+		d.Metadata[attribute] = metadataValue(raw)
+	}
+	return nil
+}
+
+// metadataValue returns strings as they are and any other JSON value in its compact form
+func metadataValue(raw json.RawMessage) string {
+	var value string
+	if err := json.Unmarshal(raw, &value); err == nil {
+		return value
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return string(raw)
+	}
+	return compact.String()
+}
+
+// ignoredMetadata returns attributes which change too often to be versioned, like traffic counters
+func ignoredMetadata() map[string]bool {
+	ignored := make(map[string]bool)
+	for _, attribute := range viper.GetStringSlice("metadata.ignored-attributes") {
+		ignored[attribute] = true
+	}
+	return ignored
+}
+
+// diffMetadata compares reported attributes with the current ones. It returns ids of values which are
+// no longer valid and new values which became valid at currentTime.
+func diffMetadata(key string, reported map[string]string, current map[string]NodeMetadata, ignored map[string]bool, currentTime time.Time) ([]uint, []NodeMetadata) {
+	var (
+		closed  []uint
+		created []NodeMetadata
+	)
+	for attribute, value := range reported {
+		if ignored[attribute] {
+			continue
+		}
+		existing, found := current[attribute]
+		if found && existing.Value == value {
+			continue
+		}
+		if found {
+			closed = append(closed, existing.Id)
+		}
+		created = append(created, NodeMetadata{
+			NodeId:    key,
+			Attribute: attribute,
+			Value:     value,
+			ValidFrom: currentTime,
+		})
+	}
+	for attribute, existing := range current {
+		if _, reportedNow := reported[attribute]; !reportedNow || ignored[attribute] {
+			closed = append(closed, existing.Id)
+		}
+	}
+	return closed, created
+}
+
+func (ns *Service) getNodeMetadata(nodeKey string) (NodeMetadataResponse, error) {
+	history, err := ns.db.findNodeMetadata(nodeKey)
+	if err != nil {
+		return NodeMetadataResponse{}, errCannotLoadDataFromDatabase
+	}
+	response := NodeMetadataResponse{
+		Key:     nodeKey,
+		Current: make(map[string]string),
+		History: history,
+	}
+	for _, metadata := range history {
+		if metadata.ValidTo == nil {
+			response.Current[metadata.Attribute] = metadata.Value
+		}
+	}
+	return response, nil
+}
+
+type NodeMetadataResponse struct {
+	Key     string            `json:"key"`
+	Current map[string]string `json:"current"`
+	History []NodeMetadata    `json:"history"`
+}
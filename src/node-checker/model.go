@@ -8,6 +8,7 @@ type Node struct {
 	Key            string          `gorm:"primary_key" json:"key"`
 	Online         bool            `json:"online"`
 	LastCheck      time.Time       `json:"lastCheck"`
+	LastHeartbeat  *time.Time      `json:"lastHeartbeat,omitempty"`
 	CreatedAt      time.Time       `json:"-"`
 	UpdatedAt      time.Time       `json:"-"`
 	DeletedAt      *time.Time      `json:"-"`
@@ -36,4 +37,194 @@ type MonthlyUptime struct {
 	Percentage     float64    `json:"percentage"`
 	Downtime       int        `json:"downtime"`
 	LastStartTime  int        `json:"lastStartTime"`
+	Excused        int        `json:"excused"`
+	Adjusted       int        `json:"adjusted"`
+}
+
+// CollectorGap is a period in which the collector was not able to observe nodes, either because
+// fetching from discovery failed or because the service itself was not running
+type CollectorGap struct {
+	Id        uint       `gorm:"primary_key" json:"id"`
+	StartedAt time.Time  `json:"startedAt"`
+	EndedAt   time.Time  `json:"endedAt"`
+	Reason    string     `json:"reason"`
+	CreatedAt time.Time  `json:"-"`
+	UpdatedAt time.Time  `json:"-"`
+	DeletedAt *time.Time `json:"-"`
+}
+
+// MaintenanceWindow is a period declared by admins in which nodes could not run through no fault of their
+// operators, for example a mandatory update or a discovery incident. A window without node key applies to every node.
+type MaintenanceWindow struct {
+	Id        uint      `gorm:"primary_key" json:"id"`
+	NodeKey   *string   `json:"nodeKey"`
+	StartedAt time.Time `json:"startedAt"`
+	EndedAt   time.Time `json:"endedAt"`
+	Reason    string    `json:"reason"`
+	CreatedBy string    `json:"createdBy"`
+	UpdatedBy string    `json:"updatedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// UptimeAdjustment is a manual credit or debit of uptime seconds of a node spread over a period. Adjustments
+// are never deleted, a reverted one keeps who reverted it and why.
+type UptimeAdjustment struct {
+	Id           uint       `gorm:"primary_key" json:"id"`
+	NodeKey      string     `json:"nodeKey"`
+	Kind         string     `json:"kind"`
+	Seconds      int64      `json:"seconds"`
+	StartedAt    time.Time  `json:"startedAt"`
+	EndedAt      time.Time  `json:"endedAt"`
+	Reason       string     `json:"reason"`
+	Ticket       string     `json:"ticket"`
+	CreatedBy    string     `json:"createdBy"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	RevertedBy   *string    `json:"revertedBy,omitempty"`
+	RevertedAt   *time.Time `json:"revertedAt,omitempty"`
+	RevertReason *string    `json:"revertReason,omitempty"`
+}
+
+// CollectionRun is a report of a single collection run
+type CollectionRun struct {
+	Id                 uint       `gorm:"primary_key" json:"id"`
+	StartedAt          time.Time  `json:"startedAt"`
+	FinishedAt         time.Time  `json:"finishedAt"`
+	Source             string     `json:"source"`
+	HttpStatus         int        `json:"httpStatus"`
+	NodesReceived      int        `json:"nodesReceived"`
+	NodesSkipped       int        `json:"nodesSkipped"`
+	NewNodes           int        `json:"newNodes"`
+	Restarts           int        `json:"restarts"`
+	NodesMarkedOffline int        `json:"nodesMarkedOffline"`
+	Error              string     `json:"error,omitempty"`
+	CreatedAt          time.Time  `json:"-"`
+	UpdatedAt          time.Time  `json:"-"`
+	DeletedAt          *time.Time `json:"-"`
+}
+
+// NodeMetadata is a value of a single node attribute reported by discovery, valid from the time it was
+// first seen until it changed. Current value has no ValidTo.
+type NodeMetadata struct {
+	Id        uint       `gorm:"primary_key" json:"-"`
+	NodeId    string     `json:"-"`
+	Attribute string     `json:"attribute"`
+	Value     string     `json:"value"`
+	ValidFrom time.Time  `json:"validFrom"`
+	ValidTo   *time.Time `json:"validTo,omitempty"`
+	CreatedAt time.Time  `json:"-"`
+}
+
+func (NodeMetadata) TableName() string {
+	return "node_metadata"
+}
+
+// NodeEvent is a state transition of a node detected by a collection run
+type NodeEvent struct {
+	Id         uint      `gorm:"primary_key" json:"id"`
+	NodeId     string    `json:"-"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurredAt"` // best estimate of the time when the transition happened
+	DetectedAt time.Time `json:"detectedAt"`
+	RunId      *uint     `json:"runId,omitempty"`
+	CreatedAt  time.Time `json:"-"`
+}
+
+// ClosedMonth marks a month for which monthly uptimes were rolled up
+type ClosedMonth struct {
+	Id        uint      `gorm:"primary_key" json:"-"`
+	Year      int       `json:"year"`
+	Month     int       `json:"month"`
+	ClosedAt  time.Time `json:"closedAt"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+// MonthlyReport is a published canonical report of monthly uptimes of a closed month. Reports form a hash chain
+// in the order of publication, a recomputed month is published again as a new revision.
+type MonthlyReport struct {
+	Id           uint      `gorm:"primary_key" json:"sequence"`
+	Year         int       `json:"year"`
+	Month        int       `json:"month"`
+	Revision     int       `json:"revision"`
+	Timezone     string    `json:"timezone"`
+	Report       string    `json:"-"`
+	ReportHash   string    `json:"reportHash"`
+	PreviousHash string    `json:"previousHash"`
+	Hash         string    `json:"hash"`
+	MerkleRoot   string    `json:"merkleRoot"` // root of the tree over node leaves of the report
+	PublishedAt  time.Time `json:"publishedAt"`
+}
+
+// UptimeRollup holds seconds a node was running within a single hour or day bucket
+type UptimeRollup struct {
+	Id            uint      `gorm:"primary_key" json:"-"`
+	NodeId        string    `json:"-"`
+	Granularity   string    `json:"granularity"`
+	BucketStart   time.Time `json:"bucketStart"`
+	UptimeSeconds float64   `json:"uptimeSeconds"`
+	CreatedAt     time.Time `json:"-"`
+	UpdatedAt     time.Time `json:"-"`
+}
+
+// UptimeCompaction records that raw uptimes which ended before the boundary were replaced by rollups
+type UptimeCompaction struct {
+	Id              uint      `gorm:"primary_key" json:"id"`
+	CompactedBefore time.Time `json:"compactedBefore"`
+	RowCount        int64     `json:"rowCount"`
+	ArchivePath     string    `json:"archivePath"` // compressed archive of removed rows, empty when not archived
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// PayoutRun is a stored reward distribution, amounts are in droplets
+type PayoutRun struct {
+	Id          uint          `gorm:"primary_key" json:"id"`
+	Scheme      string        `json:"scheme"`
+	Policy      string        `json:"policy"`
+	StartDate   time.Time     `json:"startDate"`
+	EndDate     time.Time     `json:"endDate"`
+	Budget      int64         `json:"budget"`
+	Distributed int64         `json:"distributed"`
+	Precision   int           `json:"precision"`
+	Entries     []PayoutEntry `json:"entries,omitempty" gorm:"foreignkey:RunId"`
+	CreatedBy   string        `json:"createdBy"` // admin who requested the run, or the user running the payout command
+	CreatedAt   time.Time     `json:"createdAt"`
+	UpdatedAt   time.Time     `json:"-"`
+	DeletedAt   *time.Time    `json:"-"`
+}
+
+// PayoutEntry is the amount assigned to a single node within a payout run
+type PayoutEntry struct {
+	Id        uint      `gorm:"primary_key" json:"-"`
+	RunId     uint      `json:"-"`
+	NodeId    string    `json:"nodeId"`
+	Address   string    `json:"address"`
+	Weight    int64     `json:"weight"`
+	Amount    int64     `json:"amount"`
+	CreatedAt time.Time `json:"-"`
+}
+
+// Owner is an operator running one or more nodes. Admins who created and last updated the owner are kept,
+// as its address receives payouts of owned nodes.
+type Owner struct {
+	Id        uint      `gorm:"primary_key" json:"id"`
+	Name      string    `json:"name"`
+	Address   string    `json:"address"` // optional skycoin address receiving payouts for owned nodes
+	Nodes     []string  `gorm:"-" json:"nodes"`
+	CreatedBy string    `json:"createdBy"`
+	UpdatedBy string    `json:"updatedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+// NodeGroup is a named set of nodes, optionally belonging to an owner
+type NodeGroup struct {
+	Id        uint      `gorm:"primary_key" json:"id"`
+	Name      string    `json:"name"`
+	OwnerId   *uint     `json:"ownerId"`
+	Nodes     []string  `gorm:"-" json:"nodes"`
+	CreatedBy string    `json:"createdBy"`
+	UpdatedBy string    `json:"updatedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"-"`
 }
@@ -0,0 +1,280 @@
+package node_checker
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// validateOwner checks the requested name and the optional skycoin address
+func validateOwner(request OwnerRequest) error {
+	if strings.TrimSpace(request.Name) == "" {
+		return errInvalidName
+	}
+	if request.Address != "" {
+		if _, err := cipher.DecodeBase58Address(request.Address); err != nil {
+			return errInvalidAddress
+		}
+	}
+	return nil
+}
+
+func (ns *Service) createOwner(request OwnerRequest, admin string) (Owner, error) {
+	if err := validateOwner(request); err != nil {
+		return Owner{}, err
+	}
+	owner := Owner{Name: strings.TrimSpace(request.Name), Address: request.Address, CreatedBy: admin, UpdatedBy: admin}
+	if err := ns.db.saveOwner(&owner); err != nil {
+		return Owner{}, err
+	}
+	log.Infof("Owner %v %q with address %q created by %v", owner.Id, owner.Name, owner.Address, admin)
+	return owner, nil
+}
+
+func (ns *Service) updateOwner(id uint, request OwnerRequest, admin string) (Owner, error) {
+	if err := validateOwner(request); err != nil {
+		return Owner{}, err
+	}
+	owner, err := ns.db.findOwner(id)
+	if err != nil {
+		return Owner{}, err
+	}
+	owner.Name = strings.TrimSpace(request.Name)
+	owner.Address = request.Address
+	owner.UpdatedBy = admin
+	if err := ns.db.saveOwner(&owner); err != nil {
+		return Owner{}, err
+	}
+	log.Infof("Owner %v %q with address %q updated by %v", owner.Id, owner.Name, owner.Address, admin)
+	return owner, nil
+}
+
+func (ns *Service) deleteOwner(id uint, admin string) error {
+	if err := ns.db.deleteOwner(id); err != nil {
+		return err
+	}
+	log.Infof("Owner %v deleted by %v", id, admin)
+	return nil
+}
+
+func (ns *Service) getOwners() ([]Owner, error) {
+	owners, err := ns.db.findOwners()
+	if err != nil {
+		return nil, errCannotLoadDataFromDatabase
+	}
+	return owners, nil
+}
+
+func (ns *Service) getOwner(id uint) (Owner, error) {
+	return ns.db.findOwner(id)
+}
+
+// addOwnerNodes assigns nodes to the owner, a node has at most one owner so it is taken from the previous one
+func (ns *Service) addOwnerNodes(id uint, nodeKeys []string, admin string) (Owner, error) {
+	keys := uniqueKeys(nodeKeys)
+	if len(keys) == 0 {
+		return Owner{}, errUnableToProcessRequest
+	}
+	if _, err := ns.db.findOwner(id); err != nil {
+		return Owner{}, err
+	}
+	if err := ns.db.addOwnerNodes(id, keys, admin); err != nil {
+		return Owner{}, err
+	}
+	log.Infof("Nodes %v assigned to owner %v by %v", keys, id, admin)
+	return ns.db.findOwner(id)
+}
+
+func (ns *Service) removeOwnerNode(id uint, nodeKey string, admin string) error {
+	if err := ns.db.removeOwnerNode(id, nodeKey); err != nil {
+		return err
+	}
+	log.Infof("Node %v removed from owner %v by %v", nodeKey, id, admin)
+	return nil
+}
+
+func (ns *Service) createGroup(request GroupRequest, admin string) (NodeGroup, error) {
+	if strings.TrimSpace(request.Name) == "" {
+		return NodeGroup{}, errInvalidName
+	}
+	if request.OwnerId != nil {
+		if _, err := ns.db.findOwner(*request.OwnerId); err != nil {
+			return NodeGroup{}, err
+		}
+	}
+	group := NodeGroup{Name: strings.TrimSpace(request.Name), OwnerId: request.OwnerId, CreatedBy: admin, UpdatedBy: admin}
+	if err := ns.db.saveGroup(&group); err != nil {
+		return NodeGroup{}, err
+	}
+	log.Infof("Node group %v %q created by %v", group.Id, group.Name, admin)
+	return group, nil
+}
+
+func (ns *Service) updateGroup(id uint, request GroupRequest, admin string) (NodeGroup, error) {
+	if strings.TrimSpace(request.Name) == "" {
+		return NodeGroup{}, errInvalidName
+	}
+	if request.OwnerId != nil {
+		if _, err := ns.db.findOwner(*request.OwnerId); err != nil {
+			return NodeGroup{}, err
+		}
+	}
+	group, err := ns.db.findGroup(id)
+	if err != nil {
+		return NodeGroup{}, err
+	}
+	group.Name = strings.TrimSpace(request.Name)
+	group.OwnerId = request.OwnerId
+	group.UpdatedBy = admin
+	if err := ns.db.saveGroup(&group); err != nil {
+		return NodeGroup{}, err
+	}
+	log.Infof("Node group %v %q updated by %v", group.Id, group.Name, admin)
+	return group, nil
+}
+
+func (ns *Service) deleteGroup(id uint, admin string) error {
+	if err := ns.db.deleteGroup(id); err != nil {
+		return err
+	}
+	log.Infof("Node group %v deleted by %v", id, admin)
+	return nil
+}
+
+func (ns *Service) getGroups() ([]NodeGroup, error) {
+	groups, err := ns.db.findGroups()
+	if err != nil {
+		return nil, errCannotLoadDataFromDatabase
+	}
+	return groups, nil
+}
+
+func (ns *Service) getGroup(id uint) (NodeGroup, error) {
+	return ns.db.findGroup(id)
+}
+
+func (ns *Service) addGroupNodes(id uint, nodeKeys []string, admin string) (NodeGroup, error) {
+	keys := uniqueKeys(nodeKeys)
+	if len(keys) == 0 {
+		return NodeGroup{}, errUnableToProcessRequest
+	}
+	if _, err := ns.db.findGroup(id); err != nil {
+		return NodeGroup{}, err
+	}
+	if err := ns.db.addGroupNodes(id, keys, admin); err != nil {
+		return NodeGroup{}, err
+	}
+	log.Infof("Nodes %v added to node group %v by %v", keys, id, admin)
+	return ns.db.findGroup(id)
+}
+
+func (ns *Service) removeGroupNode(id uint, nodeKey string, admin string) error {
+	if err := ns.db.removeGroupNode(id, nodeKey); err != nil {
+		return err
+	}
+	log.Infof("Node %v removed from node group %v by %v", nodeKey, id, admin)
+	return nil
+}
+
+func (ns *Service) getOwnerUptime(id uint, startDate time.Time, endDate time.Time) (AggregateUptimeReport, error) {
+	owner, err := ns.db.findOwner(id)
+	if err != nil {
+		return AggregateUptimeReport{}, err
+	}
+	return ns.aggregateUptime(owner.Id, owner.Name, owner.Nodes, startDate, endDate)
+}
+
+func (ns *Service) getGroupUptime(id uint, startDate time.Time, endDate time.Time) (AggregateUptimeReport, error) {
+	group, err := ns.db.findGroup(id)
+	if err != nil {
+		return AggregateUptimeReport{}, err
+	}
+	return ns.aggregateUptime(group.Id, group.Name, group.Nodes, startDate, endDate)
+}
+
+// aggregateUptime sums uptimes of the nodes within the period. Contribution of every member is its share
+// of the summed uptime in percent.
+func (ns *Service) aggregateUptime(id uint, name string, nodeKeys []string, startDate time.Time, endDate time.Time) (AggregateUptimeReport, error) {
+	report := AggregateUptimeReport{
+		Id:        id,
+		Name:      name,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Members:   []MemberUptime{},
+	}
+	uptimes, err := ns.calculateUptimes(nodeKeys, startDate, endDate)
+	if err != nil {
+		return report, err
+	}
+	for _, uptime := range uptimes {
+		report.Nodes++
+		if uptime.Online {
+			report.Online++
+		}
+		report.Uptime += uptime.Uptime
+		report.Downtime += uptime.Downtime
+		report.Excused += uptime.Excused
+		report.Adjusted += uptime.Adjusted
+	}
+	report.Percentage = percentage(report.Uptime, report.Uptime+report.Downtime)
+	for _, uptime := range uptimes {
+		report.Members = append(report.Members, MemberUptime{
+			NodeUptimeResponse: uptime,
+			Contribution:       percentage(uptime.Uptime, report.Uptime),
+		})
+	}
+	return report, nil
+}
+
+// uniqueKeys trims keys and returns the non-empty ones without duplicates, sorted
+func uniqueKeys(nodeKeys []string) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, key := range nodeKeys {
+		key = strings.TrimSpace(key)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type OwnerRequest struct {
+	Name    string `json:"name" binding:"required"`
+	Address string `json:"address"`
+}
+
+type GroupRequest struct {
+	Name    string `json:"name" binding:"required"`
+	OwnerId *uint  `json:"ownerId"`
+}
+
+type NodeKeysRequest struct {
+	Nodes []string `json:"nodes" binding:"required"`
+}
+
+type MemberUptime struct {
+	NodeUptimeResponse
+	Contribution float64 `json:"contribution"`
+}
+
+type AggregateUptimeReport struct {
+	Id         uint           `json:"id"`
+	Name       string         `json:"name"`
+	StartDate  time.Time      `json:"startDate"`
+	EndDate    time.Time      `json:"endDate"`
+	Nodes      int            `json:"nodes"`
+	Online     int            `json:"online"`
+	Uptime     float64        `json:"uptime"`
+	Downtime   float64        `json:"downtime"`
+	Excused    float64        `json:"excused"`
+	Adjusted   float64        `json:"adjusted"`
+	Percentage float64        `json:"percentage"`
+	Members    []MemberUptime `json:"members"`
+}
@@ -0,0 +1,352 @@
+package node_checker
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/spf13/viper"
+)
+
+// Weighting schemes of payouts
+const (
+	PayoutSchemeEqual  = "equal"           // every eligible node gets the same share
+	PayoutSchemeUptime = "uptime-weighted" // shares are proportional to uptime seconds within the period
+	PayoutSchemeTiered = "tiered"          // shares are proportional to weight of the tier reached by uptime percentage
+)
+
+// ExportFormatSkycoin writes address,coins lines accepted by skycoin-cli createRawTransaction --csv
+const ExportFormatSkycoin = "skycoin"
+
+// dropletsPerCoin is the number of droplets, the smallest skycoin unit, in a single coin
+const dropletsPerCoin = 1000000
+
+// maxCoinDecimals is the number of decimal places representable in droplets
+const maxCoinDecimals = 6
+
+// payoutTier assigns the weight to nodes with at least the minimal uptime percentage
+type payoutTier struct {
+	MinPercentage float64 `mapstructure:"min-percentage"`
+	Weight        int64   `mapstructure:"weight"`
+}
+
+// payoutTiers returns configured tiers ordered from the highest minimal percentage
+func payoutTiers() []payoutTier {
+	var tiers []payoutTier
+	if err := viper.UnmarshalKey("payouts.tiers", &tiers); err != nil {
+		log.Errorf("Unable to read payout tiers from configuration - %v", err)
+	}
+	sort.SliceStable(tiers, func(i, j int) bool { return tiers[i].MinPercentage > tiers[j].MinPercentage })
+	return tiers
+}
+
+// payoutPrecision returns the number of decimal places of paid amounts, skycoin transactions accept 3 by default
+func payoutPrecision() int {
+	if !viper.IsSet("payouts.precision") {
+		return 3
+	}
+	precision := viper.GetInt("payouts.precision")
+	if precision < 0 {
+		return 0
+	}
+	if precision > maxCoinDecimals {
+		return maxCoinDecimals
+	}
+	return precision
+}
+
+// payoutAddressAttribute returns the node metadata attribute which holds the owner address
+func payoutAddressAttribute() string {
+	if attribute := viper.GetString("payouts.address-attribute"); attribute != "" {
+		return attribute
+	}
+	return "reward_address"
+}
+
+// parseCoins parses a decimal amount of coins into droplets
+func parseCoins(value string) (int64, error) {
+	parts := strings.SplitN(strings.TrimSpace(value), ".", 2)
+	whole, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || whole < 0 || whole > (1<<62)/dropletsPerCoin {
+		return 0, errInvalidBudget
+	}
+	var fraction int64
+	if len(parts) == 2 {
+		digits := parts[1]
+		if len(digits) == 0 || len(digits) > maxCoinDecimals || strings.Trim(digits, "0123456789") != "" {
+			return 0, errInvalidBudget
+		}
+		digits += strings.Repeat("0", maxCoinDecimals-len(digits))
+		if fraction, err = strconv.ParseInt(digits, 10, 64); err != nil {
+			return 0, errInvalidBudget
+		}
+	}
+	return whole*dropletsPerCoin + fraction, nil
+}
+
+// formatCoins formats droplets as a decimal amount of coins without trailing zeros
+func formatCoins(droplets int64) string {
+	value := fmt.Sprintf("%d.%06d", droplets/dropletsPerCoin, droplets%dropletsPerCoin)
+	return strings.TrimSuffix(strings.TrimRight(value, "0"), ".")
+}
+
+// distribute splits total units proportionally to weights by the largest remainder method. Units left after
+// rounding down go one by one to entries with the largest remainder, ties go to the lower index, so the result
+// depends only on the order of weights.
+func distribute(total int64, weights []int64) []int64 {
+	amounts := make([]int64, len(weights))
+	sum := new(big.Int)
+	for _, weight := range weights {
+		sum.Add(sum, big.NewInt(weight))
+	}
+	if sum.Sign() <= 0 || total <= 0 {
+		return amounts
+	}
+
+	remainders := make([]*big.Int, len(weights))
+	distributed := int64(0)
+	for i, weight := range weights {
+		share, remainder := new(big.Int).QuoRem(new(big.Int).Mul(big.NewInt(total), big.NewInt(weight)), sum, new(big.Int))
+		amounts[i] = share.Int64()
+		remainders[i] = remainder
+		distributed += amounts[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]].Cmp(remainders[order[b]]) > 0 })
+	for i := 0; int64(i) < total-distributed; i++ {
+		amounts[order[i]]++
+	}
+	return amounts
+}
+
+// schemeWeight returns the weight of an eligible node in the scheme
+func schemeWeight(scheme string, node NodeEligibility, tiers []payoutTier) int64 {
+	switch scheme {
+	case PayoutSchemeUptime:
+		return int64(node.Uptime)
+	case PayoutSchemeTiered:
+		for _, tier := range tiers {
+			if node.Percentage >= tier.MinPercentage {
+				return tier.Weight
+			}
+		}
+		return 0
+	default:
+		return 1
+	}
+}
+
+// validPayoutAddress tells whether the address is a skycoin address which can be paid
+func validPayoutAddress(address string) bool {
+	_, err := cipher.DecodeBase58Address(address)
+	return err == nil
+}
+
+// calculatePayout distributes the budget among nodes eligible by the policy within the month and stores the run
+// together with who created it. Nodes are paid to the address of their owner, or to the address in their metadata
+// when the owner has none. Nodes without a valid address are stored with their weight and zero amount, their share
+// is distributed among the paid nodes. Runs which would pay nothing are not stored.
+func (ns *Service) calculatePayout(request PayoutRequest, createdBy string) (PayoutRun, error) {
+	budget, err := parseCoins(request.Budget)
+	if err != nil || budget <= 0 {
+		return PayoutRun{}, errInvalidBudget
+	}
+	if request.Scheme != PayoutSchemeEqual && request.Scheme != PayoutSchemeUptime && request.Scheme != PayoutSchemeTiered {
+		return PayoutRun{}, errUnknownPayoutScheme
+	}
+	tiers := payoutTiers()
+	if request.Scheme == PayoutSchemeTiered && len(tiers) == 0 {
+		return PayoutRun{}, errNoPayoutTiers
+	}
+	policy, err := findEligibilityPolicy(request.Policy)
+	if err != nil {
+		return PayoutRun{}, err
+	}
+	period, location, err := reportingMonth(request.Month, request.Timezone)
+	if err != nil {
+		return PayoutRun{}, err
+	}
+
+	report, err := ns.evaluateEligibility(policy, period.start(location), period.end(location), location)
+	if err != nil {
+		return PayoutRun{}, err
+	}
+	eligible := report.Eligible
+	sort.Slice(eligible, func(i, j int) bool { return eligible[i].Key < eligible[j].Key })
+	keys := make([]string, 0, len(eligible))
+	for _, node := range eligible {
+		keys = append(keys, node.Key)
+	}
+	metadata, err := ns.db.findCurrentMetadata(keys)
+	if err != nil {
+		return PayoutRun{}, errCannotLoadDataFromDatabase
+	}
+	owners, err := ns.db.findNodeOwners(keys)
+	if err != nil {
+		return PayoutRun{}, errCannotLoadDataFromDatabase
+	}
+
+	attribute := payoutAddressAttribute()
+	entries := make([]PayoutEntry, 0, len(eligible))
+	weights := make([]int64, 0, len(eligible))
+	for _, node := range eligible {
+		entry := PayoutEntry{
+			NodeId:  node.Key,
+			Address: owners[node.Key].Address,
+			Weight:  schemeWeight(request.Scheme, node, tiers),
+		}
+		if entry.Address == "" {
+			entry.Address = metadata[node.Key][attribute].Value
+		}
+		payable := entry.Weight
+		if entry.Address == "" {
+			payable = 0
+		} else if !validPayoutAddress(entry.Address) {
+			log.Warnf("Node %v has invalid payout address %q, it is not paid", node.Key, entry.Address)
+			payable = 0
+		}
+		entries = append(entries, entry)
+		weights = append(weights, payable)
+	}
+
+	precision := payoutPrecision()
+	unit := int64(1)
+	for i := precision; i < maxCoinDecimals; i++ {
+		unit *= 10
+	}
+	run := PayoutRun{
+		Scheme:    request.Scheme,
+		Policy:    policy.Name,
+		StartDate: report.StartDate,
+		EndDate:   report.EndDate,
+		Budget:    budget,
+		Precision: precision,
+		CreatedBy: createdBy,
+	}
+	for i, amount := range distribute(budget/unit, weights) {
+		entries[i].Amount = amount * unit
+		run.Distributed += entries[i].Amount
+	}
+	run.Entries = entries
+	if run.Distributed == 0 {
+		return PayoutRun{}, errNothingToPay
+	}
+
+	if err := ns.db.createPayoutRun(&run); err != nil {
+		return PayoutRun{}, errCannotSavePayout
+	}
+	log.Infof("Payout run %v created by %v distributed %v of %v coins among %v nodes", run.Id, createdBy, formatCoins(run.Distributed), formatCoins(budget), len(entries))
+	return run, nil
+}
+
+func (ns *Service) getPayoutRuns(page int, pageSize int) (PayoutRunsPage, error) {
+	runs, total, err := ns.db.findPayoutRuns(page, pageSize)
+	if err != nil {
+		return PayoutRunsPage{}, errCannotLoadDataFromDatabase
+	}
+	return PayoutRunsPage{
+		Runs:     runs,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+func (ns *Service) getPayoutRun(id uint) (PayoutRun, error) {
+	return ns.db.findPayoutRun(id)
+}
+
+// payoutAddresses sums paid amounts per owner address, ordered by address
+func payoutAddresses(run PayoutRun) []AddressPayout {
+	byAddress := make(map[string]*AddressPayout)
+	var addresses []string
+	for _, entry := range run.Entries {
+		if entry.Address == "" || entry.Amount == 0 {
+			continue
+		}
+		payout, found := byAddress[entry.Address]
+		if !found {
+			payout = &AddressPayout{Address: entry.Address}
+			byAddress[entry.Address] = payout
+			addresses = append(addresses, entry.Address)
+		}
+		payout.Amount += entry.Amount
+		payout.Nodes = append(payout.Nodes, entry.NodeId)
+	}
+	sort.Strings(addresses)
+	result := make([]AddressPayout, 0, len(addresses))
+	for _, address := range addresses {
+		result = append(result, *byAddress[address])
+	}
+	return result
+}
+
+// CreatePayout calculates and stores a payout run created by the given user
+func CreatePayout(request PayoutRequest, createdBy string) (PayoutRun, error) {
+	ns := DefaultService()
+	return ns.calculatePayout(request, createdBy)
+}
+
+// WritePayout writes the payout run per node as csv or json, or per owner address as skycoin CLI batch file
+func WritePayout(w io.Writer, run PayoutRun, format string) error {
+	switch format {
+	case ExportFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(PayoutReport{Run: run, Addresses: payoutAddresses(run)})
+	case ExportFormatCSV:
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"node_id", "address", "weight", "amount"})
+		for _, entry := range run.Entries {
+			writer.Write([]string{entry.NodeId, entry.Address, strconv.FormatInt(entry.Weight, 10), formatCoins(entry.Amount)})
+		}
+		writer.Flush()
+		return writer.Error()
+	case ExportFormatSkycoin:
+		writer := csv.NewWriter(w)
+		for _, payout := range payoutAddresses(run) {
+			writer.Write([]string{payout.Address, formatCoins(payout.Amount)})
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		return errUnknownExportFormat
+	}
+}
+
+type PayoutRequest struct {
+	Budget   string `json:"budget" binding:"required"` // amount of coins, up to 6 decimal places
+	Policy   string `json:"policy" binding:"required"`
+	Scheme   string `json:"scheme" binding:"required"`
+	Month    string `json:"month"`    // YYYY-MM, previous month when empty
+	Timezone string `json:"timezone"` // configured reporting timezone when empty
+}
+
+type AddressPayout struct {
+	Address string   `json:"address"`
+	Amount  int64    `json:"amount"`
+	Nodes   []string `json:"nodes"`
+}
+
+type PayoutReport struct {
+	Run       PayoutRun       `json:"run"`
+	Addresses []AddressPayout `json:"addresses"`
+}
+
+type PayoutRunsPage struct {
+	Runs     []PayoutRun `json:"runs"`
+	Total    int         `json:"total"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"pageSize"`
+}
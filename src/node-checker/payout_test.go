@@ -0,0 +1,90 @@
+package node_checker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDistribute(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    int64
+		weights  []int64
+		expected []int64
+	}{
+		{name: "exact shares", total: 10, weights: []int64{1, 2, 3, 4}, expected: []int64{1, 2, 3, 4}},
+		{name: "tie goes to the lower index", total: 10, weights: []int64{1, 1, 1}, expected: []int64{4, 3, 3}},
+		{name: "several ties", total: 100, weights: []int64{1, 1, 1, 1, 1, 1}, expected: []int64{17, 17, 17, 17, 16, 16}},
+		{name: "largest remainders first", total: 5, weights: []int64{3, 2, 1}, expected: []int64{2, 2, 1}},
+		{name: "zero weight gets nothing", total: 7, weights: []int64{5, 0, 2}, expected: []int64{5, 0, 2}},
+		{name: "weights which overflow int64 when multiplied", total: 1 << 62, weights: []int64{1 << 62, 1 << 62}, expected: []int64{1 << 61, 1 << 61}},
+		{name: "no weights", total: 100, weights: []int64{0, 0}, expected: []int64{0, 0}},
+		{name: "nothing to distribute", total: 0, weights: []int64{1, 2}, expected: []int64{0, 0}},
+		{name: "no entries", total: 100, weights: []int64{}, expected: []int64{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			amounts := distribute(test.total, test.weights)
+			if !reflect.DeepEqual(amounts, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, amounts)
+			}
+			if again := distribute(test.total, test.weights); !reflect.DeepEqual(amounts, again) {
+				t.Errorf("expected the same result for the same weights, got %v and %v", amounts, again)
+			}
+		})
+	}
+}
+
+func TestDistributeWholeTotal(t *testing.T) {
+	weights := []int64{86400, 3600, 7, 1, 2592000, 123456, 0, 999}
+	for total := int64(1); total < 5000; total += 37 {
+		sum := int64(0)
+		for _, amount := range distribute(total, weights) {
+			sum += amount
+		}
+		if sum != total {
+			t.Fatalf("expected %v to be distributed, got %v", total, sum)
+		}
+	}
+}
+
+func TestParseCoins(t *testing.T) {
+	valid := map[string]int64{
+		"1":             1000000,
+		"0.5":           500000,
+		"1.000001":      1000001,
+		" 2.25 ":        2250000,
+		"0":             0,
+		"4611686018427": 4611686018427000000,
+	}
+	for value, expected := range valid {
+		if droplets, err := parseCoins(value); err != nil || droplets != expected {
+			t.Errorf("%q: expected %v droplets, got %v and error %v", value, expected, droplets, err)
+		}
+	}
+	for _, value := range []string{"", "abc", "-1", "1.", ".5", "1.1234567", "1.2a", "1.-5", "4611686018428"} {
+		if _, err := parseCoins(value); err != errInvalidBudget {
+			t.Errorf("%q: expected %v, got %v", value, errInvalidBudget, err)
+		}
+	}
+}
+
+func TestFormatCoins(t *testing.T) {
+	tests := map[int64]string{
+		0:          "0",
+		1:          "0.000001",
+		1000000:    "1",
+		1500000:    "1.5",
+		10000000:   "10",
+		100000000:  "100",
+		1234567890: "1234.56789",
+	}
+	for droplets, expected := range tests {
+		if actual := formatCoins(droplets); actual != expected {
+			t.Errorf("%v: expected %q, got %q", droplets, expected, actual)
+		}
+		if parsed, err := parseCoins(formatCoins(droplets)); err != nil || parsed != droplets {
+			t.Errorf("%v: expected formatted coins to parse back, got %v and error %v", droplets, parsed, err)
+		}
+	}
+}
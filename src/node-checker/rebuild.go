@@ -0,0 +1,195 @@
+package node_checker
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Rebuild replays all archived snapshots in order into the connected database, which has to be empty,
+// and regenerates monthly uptimes for every month closed within the replayed period. Maintenance windows and
+// uptime adjustments are copied from the source schema first, as they are not part of the snapshots. Snapshots
+// are written by the instance which received the payload, so the rebuild fails when a collection run of the
+// source schema has no snapshot in the archive rather than replaying a partial history.
+func Rebuild(sourceSchema string) error {
+	archive := NewSnapshots(viper.GetString("snapshots.dir"), 0)
+	ns := DefaultService()
+
+	nodes, err := ns.db.findNodes()
+	if err != nil && err != errCannotLoadDataFromDatabase {
+		return err
+	}
+	if len(nodes) > 0 {
+		return errRebuildTargetNotEmpty
+	}
+	runs, err := ns.db.findSchemaCollectionRuns(sourceSchema)
+	if err != nil {
+		return err
+	}
+	snapshots, err := archive.list()
+	if err != nil {
+		return err
+	}
+	if missing := missingSnapshots(runs, snapshots); len(missing) > 0 {
+		log.Errorf("%v collection runs have no snapshot in %v, the first one is run %v started at %v",
+			len(missing), archive.dir, missing[0].Id, missing[0].StartedAt)
+		return errSnapshotsMissing
+	}
+	if err := ns.db.copyMaintenanceAndAdjustments(sourceSchema); err != nil {
+		return err
+	}
+
+	first, last, err := ns.replaySnapshots(archive, snapshots)
+	if err != nil {
+		return err
+	}
+	if first.IsZero() {
+		log.Warn("No snapshots found, nothing to rebuild")
+		return nil
+	}
+
+	location := reportingLocation()
+	month := monthOf(first, location)
+	for !month.end(location).After(last) {
+		if err := ns.rollupMonth(month); err != nil {
+			return err
+		}
+		month = month.next()
+	}
+	return nil
+}
+
+// missingSnapshots returns finished collection runs without a snapshot of a node source or a failed run taken
+// between their start and finish. Heartbeats are not collection runs, so their snapshots are not checked.
+func missingSnapshots(runs []CollectionRun, snapshots []snapshot) []CollectionRun {
+	var collected []time.Time
+	for _, s := range snapshots {
+		if s.Source != snapshotHeartbeat {
+			collected = append(collected, s.TakenAt)
+		}
+	}
+	var missing []CollectionRun
+	for _, run := range runs {
+		if !run.FinishedAt.After(run.StartedAt) {
+			continue // the run was interrupted before it finished and its outcome is unknown
+		}
+		i := sort.Search(len(collected), func(i int) bool { return !collected[i].Before(run.StartedAt) })
+		if i == len(collected) || collected[i].After(run.FinishedAt) {
+			missing = append(missing, run)
+		}
+	}
+	return missing
+}
+
+// replaySnapshots applies archived snapshots run by run and returns the time of the first and the last one
+func (ns *Service) replaySnapshots(archive *snapshotArchive, snapshots []snapshot) (time.Time, time.Time, error) {
+	if len(snapshots) == 0 {
+		return time.Time{}, time.Time{}, nil
+	}
+
+	for start := 0; start < len(snapshots); {
+		end := start
+		for end < len(snapshots) && snapshots[end].TakenAt.Equal(snapshots[start].TakenAt) {
+			end++
+		}
+		if err := ns.replayRun(archive, snapshots[start:end]); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		start = end
+	}
+	return snapshots[0].TakenAt, snapshots[len(snapshots)-1].TakenAt, nil
+}
+
+// replayRun merges snapshots taken by a single run and applies them as if they were just collected.
+// Heartbeats and failed runs are replayed the same way as they were handled when received.
+func (ns *Service) replayRun(archive *snapshotArchive, snapshots []snapshot) error {
+	takenAt := snapshots[0].TakenAt
+	run := CollectionRun{StartedAt: takenAt}
+
+	var collected []snapshot
+	for _, s := range snapshots {
+		switch s.Source {
+		case snapshotFailedRun:
+			ns.startCollectionRun(&run)
+			ns.recordFailedRun(takenAt)
+			ns.finishCollectionRun(&run, errCannotLoadData)
+			log.Infof("Replayed failed run from %v", takenAt)
+		case snapshotHeartbeat:
+			if err := ns.replayHeartbeat(archive, s); err != nil {
+				return err
+			}
+		default:
+			collected = append(collected, s)
+		}
+	}
+	if len(collected) == 0 {
+		return nil
+	}
+	snapshots = collected
+
+	// responses are merged in the priority order of configured sources
+	priority := make(map[string]int)
+	for i, source := range ns.sources.sources {
+		priority[source.Name()] = i + 1
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		pi, pj := priority[snapshots[i].Source], priority[snapshots[j].Source]
+		return pi != 0 && (pj == 0 || pi < pj)
+	})
+
+	var (
+		responses []NodeResponse
+		names     []string
+	)
+	for _, s := range snapshots {
+		payload, err := archive.read(s)
+		if err != nil {
+			log.Errorf("Unable to read snapshot %v - %v", s.Path, err)
+			continue
+		}
+		res, err := extractUptimesFromURL(payload)
+		if err != nil {
+			log.Errorf("Unable to decode snapshot %v - %v", s.Path, err)
+			continue
+		}
+		responses = append(responses, *res)
+		names = append(names, s.Source)
+	}
+	if len(responses) == 0 {
+		return nil
+	}
+
+	run.Source = strings.Join(names, ",")
+	ns.startCollectionRun(&run)
+	merged, err := ns.sources.merge(responses)
+	if err != nil {
+		ns.recordFailedRun(takenAt)
+		run.FinishedAt = takenAt
+		ns.finishCollectionRun(&run, err)
+		return nil
+	}
+	err = ns.applyCollection(merged, takenAt, &run)
+	run.FinishedAt = takenAt
+	ns.finishCollectionRun(&run, err)
+	log.Infof("Replayed run from %v with %v nodes", takenAt, run.NodesReceived)
+	return err
+}
+
+// replayHeartbeat applies the node pushed by an archived heartbeat
+func (ns *Service) replayHeartbeat(archive *snapshotArchive, s snapshot) error {
+	payload, err := archive.read(s)
+	if err != nil {
+		log.Errorf("Unable to read snapshot %v - %v", s.Path, err)
+		return nil
+	}
+	res, err := extractUptimesFromURL(payload)
+	if err != nil {
+		log.Errorf("Unable to decode snapshot %v - %v", s.Path, err)
+		return nil
+	}
+	run := CollectionRun{StartedAt: s.TakenAt}
+	return ns.persistNodes(*res, s.TakenAt, &run, true)
+}
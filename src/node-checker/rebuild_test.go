@@ -0,0 +1,36 @@
+package node_checker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMissingSnapshots(t *testing.T) {
+	at := func(minutes int) time.Time {
+		return time.Date(2026, time.October, 17, 10, minutes, 0, 0, time.UTC)
+	}
+	runs := []CollectionRun{
+		{Id: 1, StartedAt: at(0), FinishedAt: at(1)},
+		{Id: 2, StartedAt: at(5), FinishedAt: at(6)},
+		{Id: 3, StartedAt: at(10), FinishedAt: at(11)},
+		{Id: 4, StartedAt: at(15), FinishedAt: at(15)},
+		{Id: 5, StartedAt: at(20), FinishedAt: at(21)},
+	}
+	snapshots := []snapshot{
+		{Source: "discovery", TakenAt: at(0).Add(10 * time.Second)},
+		{Source: "backup", TakenAt: at(0).Add(10 * time.Second)},
+		{Source: snapshotHeartbeat, TakenAt: at(5).Add(30 * time.Second)},
+		{Source: snapshotFailedRun, TakenAt: at(10).Add(20 * time.Second)},
+		{Source: "discovery", TakenAt: at(21)},
+	}
+	missing := missingSnapshots(runs, snapshots)
+	if len(missing) != 1 || missing[0].Id != 2 {
+		t.Fatalf("expected only run 2 to miss its snapshot, got %v", missing)
+	}
+	if missing := missingSnapshots(runs, nil); len(missing) != 4 {
+		t.Errorf("expected every finished run to miss its snapshot, got %v", missing)
+	}
+	if missing := missingSnapshots(nil, snapshots); len(missing) != 0 {
+		t.Errorf("expected no missing snapshots without runs, got %v", missing)
+	}
+}
@@ -0,0 +1,250 @@
+package node_checker
+
+import (
+	"encoding/csv"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Kinds of reconciled sources, given as kind:argument
+const (
+	ReconcileSourceCSV     = "csv"     // csv:path to an export file
+	ReconcileSourceLive    = "live"    // live:YYYY-MM computed from raw uptimes, nodes without uptime are left out like in monthly uptimes
+	ReconcileSourceMonthly = "monthly" // monthly:YYYY-MM stored monthly uptimes
+)
+
+// Reconciled fields
+const (
+	ReconcileFieldUptime     = "uptime"
+	ReconcileFieldDowntime   = "downtime"
+	ReconcileFieldPercentage = "percentage"
+)
+
+// ReconcileOptions configures how two sources are compared
+type ReconcileOptions struct {
+	Field       string  // compared field, uptime, downtime or percentage
+	Tolerance   float64 // absolute difference which is not reported
+	Timezone    string  // timezone in which months of live sources are cut, configured one when empty
+	KeyColumn   string  // header name or zero based index of the node key column in csv sources
+	ValueColumn string  // header name or zero based index of the compared column in csv sources, field name when empty
+}
+
+// Reconcile loads values of the field from both sources, matches them by node key and reports keys missing
+// in either of them together with values which differ by more than the tolerance
+func Reconcile(left string, right string, options ReconcileOptions) (ReconcileReport, error) {
+	ns := DefaultService()
+	return ns.reconcile(left, right, options)
+}
+
+func (ns *Service) reconcile(left string, right string, options ReconcileOptions) (ReconcileReport, error) {
+	if options.Field == "" {
+		options.Field = ReconcileFieldUptime
+	}
+	if options.Field != ReconcileFieldUptime && options.Field != ReconcileFieldDowntime && options.Field != ReconcileFieldPercentage {
+		return ReconcileReport{}, errUnknownReconcileField
+	}
+	leftValues, err := ns.reconcileValues(left, options)
+	if err != nil {
+		return ReconcileReport{}, err
+	}
+	rightValues, err := ns.reconcileValues(right, options)
+	if err != nil {
+		return ReconcileReport{}, err
+	}
+	return compareValues(left, right, leftValues, rightValues, options), nil
+}
+
+// reconcileValues loads the compared field of every node from the source
+func (ns *Service) reconcileValues(source string, options ReconcileOptions) (map[string]float64, error) {
+	parts := strings.SplitN(source, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, errInvalidReconcileSource
+	}
+	kind, argument := parts[0], parts[1]
+	switch kind {
+	case ReconcileSourceCSV:
+		valueColumn := options.ValueColumn
+		if valueColumn == "" {
+			valueColumn = options.Field
+		}
+		keyColumn := options.KeyColumn
+		if keyColumn == "" {
+			keyColumn = "key"
+		}
+		return readCSVValues(argument, keyColumn, valueColumn)
+	case ReconcileSourceLive:
+		month, location, err := reportingMonth(argument, options.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		uptimes, err := ns.exportAllNodesUptimes(month.start(location), month.end(location), location)
+		if err != nil {
+			return nil, err
+		}
+		values := make(map[string]float64, len(uptimes))
+		for _, uptime := range uptimes {
+			if uptime.Uptime == 0 {
+				continue // monthly uptimes are stored only for nodes which were running
+			}
+			switch options.Field {
+			case ReconcileFieldDowntime:
+				values[uptime.Key] = uptime.Downtime
+			case ReconcileFieldPercentage:
+				values[uptime.Key] = uptime.Percentage
+			default:
+				values[uptime.Key] = uptime.Uptime
+			}
+		}
+		return values, nil
+	case ReconcileSourceMonthly:
+		month, err := parseYearMonth(argument)
+		if err != nil {
+			return nil, err
+		}
+		monthlyUptimes, err := ns.db.findMonthlyUptimes(month.Year, int(month.Month))
+		if err != nil {
+			return nil, errCannotLoadDataFromDatabase
+		}
+		values := make(map[string]float64, len(monthlyUptimes))
+		for _, monthlyUptime := range monthlyUptimes {
+			switch options.Field {
+			case ReconcileFieldDowntime:
+				values[monthlyUptime.NodeId] = float64(monthlyUptime.Downtime)
+			case ReconcileFieldPercentage:
+				values[monthlyUptime.NodeId] = monthlyUptime.Percentage
+			default:
+				values[monthlyUptime.NodeId] = float64(monthlyUptime.TotalStartTime)
+			}
+		}
+		return values, nil
+	default:
+		return nil, errInvalidReconcileSource
+	}
+}
+
+// readCSVValues reads node keys and values from a csv file with a header line, columns are given
+// by header name or zero based index. Every key has to be unique.
+func readCSVValues(path string, keyColumn string, valueColumn string) (map[string]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	lines, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return map[string]float64{}, nil
+	}
+	keyIndex, err := csvColumn(lines[0], keyColumn)
+	if err != nil {
+		return nil, err
+	}
+	valueIndex, err := csvColumn(lines[0], valueColumn)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]float64, len(lines)-1)
+	for i, line := range lines[1:] {
+		if keyIndex >= len(line) || valueIndex >= len(line) {
+			return nil, errInvalidReconcileCSV
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(line[valueIndex]), 64)
+		if err != nil {
+			return nil, errInvalidReconcileCSV
+		}
+		key := strings.TrimSpace(line[keyIndex])
+		if _, found := values[key]; found {
+			log.Errorf("Key %v is repeated on line %v of %v", key, i+2, path)
+			return nil, errDuplicateReconcileKey
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+func csvColumn(header []string, column string) (int, error) {
+	if index, err := strconv.Atoi(column); err == nil && index >= 0 && index < len(header) {
+		return index, nil
+	}
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), column) {
+			return i, nil
+		}
+	}
+	return 0, errInvalidReconcileCSV
+}
+
+// compareValues matches values by node key. Deltas are ordered from the largest absolute difference.
+func compareValues(left string, right string, leftValues map[string]float64, rightValues map[string]float64, options ReconcileOptions) ReconcileReport {
+	report := ReconcileReport{
+		Left:       left,
+		Right:      right,
+		Field:      options.Field,
+		Tolerance:  options.Tolerance,
+		LeftCount:  len(leftValues),
+		RightCount: len(rightValues),
+		Missing:    []string{},
+		Extra:      []string{},
+		Deltas:     []ReconcileDelta{},
+	}
+	for key, leftValue := range leftValues {
+		rightValue, found := rightValues[key]
+		if !found {
+			report.Missing = append(report.Missing, key)
+			continue
+		}
+		report.Matched++
+		if delta := rightValue - leftValue; math.Abs(delta) > options.Tolerance {
+			report.Deltas = append(report.Deltas, ReconcileDelta{Key: key, Left: leftValue, Right: rightValue, Delta: delta})
+		}
+	}
+	for key := range rightValues {
+		if _, found := leftValues[key]; !found {
+			report.Extra = append(report.Extra, key)
+		}
+	}
+	sort.Strings(report.Missing)
+	sort.Strings(report.Extra)
+	sort.Slice(report.Deltas, func(i, j int) bool {
+		a, b := math.Abs(report.Deltas[i].Delta), math.Abs(report.Deltas[j].Delta)
+		if a != b {
+			return a > b
+		}
+		return report.Deltas[i].Key < report.Deltas[j].Key
+	})
+	return report
+}
+
+// Consistent tells whether both sources contain the same keys with values within the tolerance
+func (r ReconcileReport) Consistent() bool {
+	return len(r.Missing) == 0 && len(r.Extra) == 0 && len(r.Deltas) == 0
+}
+
+type ReconcileDelta struct {
+	Key   string  `json:"key"`
+	Left  float64 `json:"left"`
+	Right float64 `json:"right"`
+	Delta float64 `json:"delta"` // right minus left
+}
+
+type ReconcileReport struct {
+	Left       string           `json:"left"`
+	Right      string           `json:"right"`
+	Field      string           `json:"field"`
+	Tolerance  float64          `json:"tolerance"`
+	LeftCount  int              `json:"leftCount"`
+	RightCount int              `json:"rightCount"`
+	Matched    int              `json:"matched"`
+	Missing    []string         `json:"missing"` // keys present only in the left source
+	Extra      []string         `json:"extra"`   // keys present only in the right source
+	Deltas     []ReconcileDelta `json:"deltas"`
+}
@@ -0,0 +1,39 @@
+package node_checker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeReconcileCSV(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "reconcile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "export.csv")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadCSVValues(t *testing.T) {
+	path := writeReconcileCSV(t, "key,uptime\nfirst,10\n second ,20\n")
+	values, err := readCSVValues(path, "key", "uptime")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || values["first"] != 10 || values["second"] != 20 {
+		t.Errorf("unexpected values %v", values)
+	}
+}
+
+func TestReadCSVValuesDuplicateKey(t *testing.T) {
+	path := writeReconcileCSV(t, "key,uptime\nfirst,10\nsecond,20\nfirst ,30\n")
+	if _, err := readCSVValues(path, "key", "uptime"); err != errDuplicateReconcileKey {
+		t.Errorf("expected %v, got %v", errDuplicateReconcileKey, err)
+	}
+}
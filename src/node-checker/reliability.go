@@ -0,0 +1,198 @@
+package node_checker
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Fields by which the fleet reliability table can be sorted
+const (
+	ReliabilitySortKey           = "key"
+	ReliabilitySortAvailability  = "availability"
+	ReliabilitySortMTBF          = "mtbf"
+	ReliabilitySortMTTR          = "mttr"
+	ReliabilitySortLongestStreak = "longestStreak"
+	ReliabilitySortCurrentStreak = "currentStreak"
+	ReliabilitySortRestarts      = "restarts"
+	ReliabilitySortRestartRate   = "restartRate"
+)
+
+// nodeReliability computes reliability of the node within the [startDate, endDate) period from its running intervals
+// which overlap the period and the number of its restarts within it.
+// A failure is the end of a running interval within the period. The last interval is censored rather than failed
+// when it lasts until the end of the period or the node is still running in it.
+// Intervals of compacted periods are reconstructed from rollups, so failures and streaks there are approximate
+// and restarts within them are not counted.
+func nodeReliability(node Node, all []interval, restarts int, excusable []interval, adjustments []UptimeAdjustment, startDate time.Time, endDate time.Time) NodeReliability {
+	running := intersectPeriod(all, startDate, endDate)
+	uptime := nodeUptime(node, all, excusable, adjustments, startDate, endDate)
+	result := NodeReliability{
+		Key:          node.Key,
+		Online:       node.Online,
+		Uptime:       uptime.Uptime,
+		Downtime:     uptime.Downtime,
+		Availability: uptime.Percentage,
+		Excused:      uptime.Excused,
+		Adjusted:     uptime.Adjusted,
+	}
+
+	for _, i := range running {
+		if streak := i.duration().Seconds(); streak > result.LongestStreak {
+			result.LongestStreak = toFixed(streak, 0)
+		}
+	}
+	failures := len(running)
+	if last := len(running) - 1; last >= 0 && (!running[last].End.Before(endDate) ||
+		node.Online && !endDate.Before(node.LastCheck) && !running[last].End.Before(all[len(all)-1].End)) {
+		failures--
+		result.CurrentStreak = toFixed(running[last].duration().Seconds(), 0)
+	}
+	result.Failures = failures
+	if failures > 0 {
+		mtbf := toFixed(totalDuration(running).Seconds()/float64(failures), 0)
+		result.MTBF = &mtbf
+	}
+
+	var repairs []interval
+	for i := 1; i < len(running); i++ {
+		repairs = append(repairs, interval{Start: running[i-1].End, End: running[i].Start})
+	}
+	if len(repairs) > 0 {
+		mttr := toFixed(totalDuration(repairs).Seconds()/float64(len(repairs)), 0)
+		result.MTTR = &mttr
+	}
+
+	result.Restarts = restarts
+	if days := endDate.Sub(startDate).Hours() / 24; days > 0 {
+		result.RestartRate = toFixed(float64(result.Restarts)/days, 3)
+	}
+	return result
+}
+
+func (ns *Service) getNodeReliability(nodeKey string, startDate time.Time, endDate time.Time) (NodeReliability, error) {
+	node, err := ns.db.findNode(nodeKey)
+	if err != nil {
+		return NodeReliability{}, err
+	}
+	excused, err := ns.excusedPeriods(startDate, endDate)
+	if err != nil {
+		return NodeReliability{}, errCannotLoadDataFromDatabase
+	}
+	adjustments, err := ns.activeAdjustments(startDate, endDate)
+	if err != nil {
+		return NodeReliability{}, errCannotLoadDataFromDatabase
+	}
+	boundary, err := ns.compactionBoundary()
+	if err != nil {
+		return NodeReliability{}, errCannotLoadDataFromDatabase
+	}
+	all, err := ns.runningIntervals(node, boundary, startDate)
+	if err != nil {
+		return NodeReliability{}, errCannotLoadDataFromDatabase
+	}
+	restarts, err := ns.db.countUptimeRestarts(startDate, endDate)
+	if err != nil {
+		return NodeReliability{}, errCannotLoadDataFromDatabase
+	}
+	return nodeReliability(node, all, restarts[node.Key], excused.forNode(node.Key), adjustments[node.Key], startDate, endDate), nil
+}
+
+// getFleetReliability returns a page of reliability of every node sorted by the field
+func (ns *Service) getFleetReliability(startDate time.Time, endDate time.Time, sortBy string, descending bool, page int, pageSize int) (FleetReliabilityPage, error) {
+	less, found := reliabilityOrder[sortBy]
+	if !found {
+		return FleetReliabilityPage{}, errUnknownSortField
+	}
+	nodes, err := ns.db.findNodes()
+	if err != nil && err != errCannotLoadDataFromDatabase {
+		return FleetReliabilityPage{}, errCannotFindNodes
+	}
+	excused, err := ns.excusedPeriods(startDate, endDate)
+	if err != nil {
+		return FleetReliabilityPage{}, errCannotLoadDataFromDatabase
+	}
+	adjustments, err := ns.activeAdjustments(startDate, endDate)
+	if err != nil {
+		return FleetReliabilityPage{}, errCannotLoadDataFromDatabase
+	}
+	boundary, err := ns.compactionBoundary()
+	if err != nil {
+		return FleetReliabilityPage{}, errCannotLoadDataFromDatabase
+	}
+
+	intervals, err := ns.periodRunningIntervals(nodes, boundary, startDate, endDate)
+	if err != nil {
+		return FleetReliabilityPage{}, errCannotLoadDataFromDatabase
+	}
+	restarts, err := ns.db.countUptimeRestarts(startDate, endDate)
+	if err != nil {
+		return FleetReliabilityPage{}, errCannotLoadDataFromDatabase
+	}
+
+	fleet := make([]NodeReliability, 0, len(nodes))
+	for _, node := range nodes {
+		fleet = append(fleet, nodeReliability(node, intervals[node.Key], restarts[node.Key], excused.forNode(node.Key), adjustments[node.Key], startDate, endDate))
+	}
+	sort.SliceStable(fleet, func(i, j int) bool {
+		if descending {
+			return less(fleet[j], fleet[i])
+		}
+		return less(fleet[i], fleet[j])
+	})
+
+	response := FleetReliabilityPage{
+		Nodes:    []NodeReliability{},
+		Total:    len(fleet),
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if from := (page - 1) * pageSize; from < len(fleet) {
+		response.Nodes = fleet[from:minInt(from+pageSize, len(fleet))]
+	}
+	return response, nil
+}
+
+// optionalLess orders missing values after present ones in ascending order
+func optionalLess(a *float64, b *float64) bool {
+	if a == nil || b == nil {
+		return a != nil
+	}
+	return *a < *b
+}
+
+var reliabilityOrder = map[string]func(a NodeReliability, b NodeReliability) bool{
+	ReliabilitySortKey:           func(a, b NodeReliability) bool { return strings.Compare(a.Key, b.Key) < 0 },
+	ReliabilitySortAvailability:  func(a, b NodeReliability) bool { return a.Availability < b.Availability },
+	ReliabilitySortMTBF:          func(a, b NodeReliability) bool { return optionalLess(a.MTBF, b.MTBF) },
+	ReliabilitySortMTTR:          func(a, b NodeReliability) bool { return optionalLess(a.MTTR, b.MTTR) },
+	ReliabilitySortLongestStreak: func(a, b NodeReliability) bool { return a.LongestStreak < b.LongestStreak },
+	ReliabilitySortCurrentStreak: func(a, b NodeReliability) bool { return a.CurrentStreak < b.CurrentStreak },
+	ReliabilitySortRestarts:      func(a, b NodeReliability) bool { return a.Restarts < b.Restarts },
+	ReliabilitySortRestartRate:   func(a, b NodeReliability) bool { return a.RestartRate < b.RestartRate },
+}
+
+// NodeReliability holds stability metrics of a node, durations are in seconds
+type NodeReliability struct {
+	Key           string   `json:"key"`
+	Online        bool     `json:"online"`
+	Uptime        float64  `json:"uptime"`
+	Downtime      float64  `json:"downtime"`
+	Availability  float64  `json:"availability"`
+	Excused       float64  `json:"excused"`
+	Adjusted      float64  `json:"adjusted"`
+	Failures      int      `json:"failures"`
+	MTBF          *float64 `json:"mtbf"` // mean time between failures, null when the node did not fail
+	MTTR          *float64 `json:"mttr"` // mean time to recovery, null when the node did not recover within the period
+	LongestStreak float64  `json:"longestStreak"`
+	CurrentStreak float64  `json:"currentStreak"`
+	Restarts      int      `json:"restarts"`
+	RestartRate   float64  `json:"restartRate"` // restarts per day
+}
+
+type FleetReliabilityPage struct {
+	Nodes    []NodeReliability `json:"nodes"`
+	Total    int               `json:"total"`
+	Page     int               `json:"page"`
+	PageSize int               `json:"pageSize"`
+}
@@ -0,0 +1,64 @@
+package node_checker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNodeReliabilityCensoring(t *testing.T) {
+	month := YearMonth{Year: 2026, Month: time.September}
+	start, end := month.start(time.UTC), month.end(time.UTC)
+	day := 24 * time.Hour
+	tests := []struct {
+		name          string
+		node          Node
+		all           []interval
+		failures      int
+		mtbf          float64
+		currentStreak float64
+	}{
+		{
+			name:          "running through the whole month",
+			node:          Node{Online: true, LastCheck: end.Add(10 * day)},
+			all:           []interval{{Start: start.Add(-day), End: end.Add(10 * day)}},
+			failures:      0,
+			currentStreak: end.Sub(start).Seconds(),
+		},
+		{
+			name:          "running until the end of the month and offline now",
+			node:          Node{Online: false, LastCheck: end.Add(3 * day)},
+			all:           []interval{{Start: start.Add(2 * day), End: start.Add(3 * day)}, {Start: start.Add(5 * day), End: end.Add(3 * day)}},
+			failures:      1,
+			mtbf:          (day + end.Sub(start.Add(5*day))).Seconds(),
+			currentStreak: end.Sub(start.Add(5 * day)).Seconds(),
+		},
+		{
+			name:     "stopped within the month",
+			node:     Node{Online: true, LastCheck: end.Add(day)},
+			all:      []interval{{Start: start, End: start.Add(10 * day)}, {Start: end.Add(day / 2), End: end.Add(day)}},
+			failures: 1,
+			mtbf:     (10 * day).Seconds(),
+		},
+		{
+			name:          "still running in the current month",
+			node:          Node{Online: true, LastCheck: start.Add(10 * day)},
+			all:           []interval{{Start: start.Add(day), End: start.Add(10 * day)}},
+			failures:      0,
+			currentStreak: (9 * day).Seconds(),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := nodeReliability(test.node, test.all, 0, nil, nil, start, end)
+			if result.Failures != test.failures {
+				t.Errorf("expected %v failures, got %v", test.failures, result.Failures)
+			}
+			if (result.MTBF == nil) != (test.failures == 0) || result.MTBF != nil && *result.MTBF != test.mtbf {
+				t.Errorf("expected MTBF %v, got %v", test.mtbf, result.MTBF)
+			}
+			if result.CurrentStreak != test.currentStreak {
+				t.Errorf("expected current streak %v, got %v", test.currentStreak, result.CurrentStreak)
+			}
+		})
+	}
+}
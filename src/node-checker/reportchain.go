@@ -0,0 +1,239 @@
+package node_checker
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// PublishedNodeUptime is a single node line of a published monthly report
+type PublishedNodeUptime struct {
+	Key        string `json:"key"`
+	Uptime     int    `json:"uptime"`
+	Downtime   int    `json:"downtime"`
+	Excused    int    `json:"excused"`
+	Adjusted   int    `json:"adjusted"`
+	Percentage string `json:"percentage"` // 4 decimal places, so that the serialization does not depend on float formatting
+}
+
+// PublishedReport is the canonical content of a monthly report. It is serialized as compact JSON with nodes
+// ordered by key and its SHA-256 hash is chained to the hash of the previously published report.
+type PublishedReport struct {
+	Month    string                `json:"month"`
+	Timezone string                `json:"timezone"`
+	Nodes    []PublishedNodeUptime `json:"nodes"`
+}
+
+func publishedReport(month YearMonth, timezone string, monthlyUptimes []MonthlyUptime) PublishedReport {
+	report := PublishedReport{
+		Month:    month.String(),
+		Timezone: timezone,
+		Nodes:    make([]PublishedNodeUptime, 0, len(monthlyUptimes)),
+	}
+	for _, monthlyUptime := range monthlyUptimes {
+		report.Nodes = append(report.Nodes, PublishedNodeUptime{
+			Key:        monthlyUptime.NodeId,
+			Uptime:     monthlyUptime.TotalStartTime,
+			Downtime:   monthlyUptime.Downtime,
+			Excused:    monthlyUptime.Excused,
+			Adjusted:   monthlyUptime.Adjusted,
+			Percentage: strconv.FormatFloat(monthlyUptime.Percentage, 'f', 4, 64),
+		})
+	}
+	sort.Slice(report.Nodes, func(i, j int) bool { return report.Nodes[i].Key < report.Nodes[j].Key })
+	return report
+}
+
+func (r PublishedReport) canonical() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// currentReport serializes monthly uptimes of the month as they are stored now
+func (ns *Service) currentReport(month YearMonth, timezone string) ([]byte, error) {
+	monthlyUptimes, err := ns.db.findMonthlyUptimes(month.Year, int(month.Month))
+	if err != nil {
+		return nil, errCannotLoadDataFromDatabase
+	}
+	return publishedReport(month, timezone, monthlyUptimes).canonical()
+}
+
+// chainHead returns the hash of the last published report, zero hash when nothing was published yet
+func (ns *Service) chainHead() (cipher.SHA256, error) {
+	reports, err := ns.db.findMonthlyReports(false)
+	if err != nil {
+		return cipher.SHA256{}, errCannotLoadDataFromDatabase
+	}
+	if len(reports) == 0 {
+		return cipher.SHA256{}, nil
+	}
+	return cipher.SHA256FromHex(reports[len(reports)-1].Hash)
+}
+
+// publishMonthlyReport appends the report of the month to the chain. Nothing is published when stored monthly
+// uptimes match the latest revision of the month, otherwise they are published as a new revision.
+func (ns *Service) publishMonthlyReport(month YearMonth, location *time.Location) error {
+	monthlyUptimes, err := ns.db.findMonthlyUptimes(month.Year, int(month.Month))
+	if err != nil {
+		return errCannotLoadDataFromDatabase
+	}
+	published := publishedReport(month, location.String(), monthlyUptimes)
+	content, err := published.canonical()
+	if err != nil {
+		return err
+	}
+	reportHash := cipher.SumSHA256(content)
+
+	revision := 1
+	latest, err := ns.db.findMonthlyReport(month.Year, int(month.Month))
+	switch err {
+	case nil:
+		if latest.ReportHash == reportHash.Hex() {
+			return nil
+		}
+		revision = latest.Revision + 1
+		log.Warnf("Monthly uptimes of %v differ from the published revision %v, publishing revision %v", month, latest.Revision, revision)
+	case errCannotLoadDataFromDatabase:
+	default:
+		return err
+	}
+
+	previous, err := ns.chainHead()
+	if err != nil {
+		return err
+	}
+	report := MonthlyReport{
+		Year:         month.Year,
+		Month:        int(month.Month),
+		Revision:     revision,
+		Timezone:     location.String(),
+		Report:       string(content),
+		ReportHash:   reportHash.Hex(),
+		PreviousHash: previous.Hex(),
+		Hash:         cipher.AddSHA256(previous, reportHash).Hex(),
+		MerkleRoot:   merkleRoot(reportLeaves(published)).Hex(),
+		PublishedAt:  time.Now(),
+	}
+	if err := ns.db.createMonthlyReport(&report); err != nil {
+		return err
+	}
+	log.Infof("Report of %v published with hash %v", month, report.Hash)
+	return nil
+}
+
+// verifyReportChain recomputes hashes and Merkle roots of every published report and their links, and compares the latest revision
+// of every month with monthly uptimes stored now. When expected head is set, the chain has to contain it, so that
+// a chain truncated or rewritten since the head was seen is detected.
+func (ns *Service) verifyReportChain(expectedHead string) (ChainVerification, error) {
+	reports, err := ns.db.findMonthlyReports(true)
+	if err != nil {
+		return ChainVerification{}, errCannotLoadDataFromDatabase
+	}
+	verification := ChainVerification{Reports: len(reports), Problems: []ChainProblem{}}
+	problem := func(report MonthlyReport, description string) {
+		verification.Problems = append(verification.Problems, ChainProblem{
+			Sequence: report.Id,
+			Month:    YearMonth{Year: report.Year, Month: time.Month(report.Month)}.String(),
+			Revision: report.Revision,
+			Problem:  description,
+		})
+	}
+
+	previous := cipher.SHA256{}
+	latest := make(map[YearMonth]MonthlyReport)
+	headFound := expectedHead == ""
+	for _, report := range reports {
+		reportHash := cipher.SumSHA256([]byte(report.Report))
+		if reportHash.Hex() != report.ReportHash {
+			problem(report, "stored report does not match its hash")
+		}
+		if report.PreviousHash != previous.Hex() {
+			problem(report, "report is not linked to the previous report")
+		}
+		hash := cipher.AddSHA256(previous, reportHash)
+		if hash.Hex() != report.Hash {
+			problem(report, "chain hash does not match the report and the previous hash")
+		}
+		previous = hash
+		if report.MerkleRoot != "" {
+			if content, err := publishedContent(report); err != nil || merkleRoot(reportLeaves(content)).Hex() != report.MerkleRoot {
+				problem(report, "Merkle root does not match the report")
+			}
+		}
+		headFound = headFound || report.Hash == expectedHead
+		latest[YearMonth{Year: report.Year, Month: time.Month(report.Month)}] = report
+	}
+	if len(reports) > 0 {
+		verification.Head = reports[len(reports)-1].Hash
+	}
+	if !headFound {
+		verification.Problems = append(verification.Problems, ChainProblem{Problem: "chain does not contain the expected head " + expectedHead})
+	}
+
+	months := make([]YearMonth, 0, len(latest))
+	for month := range latest {
+		months = append(months, month)
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].before(months[j]) })
+	for _, month := range months {
+		report := latest[month]
+		content, err := ns.currentReport(month, report.Timezone)
+		if err != nil {
+			return ChainVerification{}, err
+		}
+		if cipher.SumSHA256(content).Hex() != report.ReportHash {
+			problem(report, "stored monthly uptimes differ from the published report")
+		}
+	}
+	verification.Valid = len(verification.Problems) == 0
+	return verification, nil
+}
+
+// VerifyReportChain verifies the chain of published monthly reports, see verifyReportChain
+func VerifyReportChain(expectedHead string) (ChainVerification, error) {
+	ns := DefaultService()
+	return ns.verifyReportChain(expectedHead)
+}
+
+func (ns *Service) getReportChain() ([]MonthlyReport, error) {
+	reports, err := ns.db.findMonthlyReports(false)
+	if err != nil {
+		return nil, errCannotLoadDataFromDatabase
+	}
+	return reports, nil
+}
+
+// getMonthlyReport returns the latest revision of the published report of the month in YYYY-MM format
+func (ns *Service) getMonthlyReport(period string) (MonthlyReportResponse, error) {
+	month, err := parseYearMonth(period)
+	if err != nil {
+		return MonthlyReportResponse{}, err
+	}
+	report, err := ns.db.findMonthlyReport(month.Year, int(month.Month))
+	if err != nil {
+		return MonthlyReportResponse{}, err
+	}
+	return MonthlyReportResponse{MonthlyReport: report, Report: json.RawMessage(report.Report)}, nil
+}
+
+type MonthlyReportResponse struct {
+	MonthlyReport
+	Report json.RawMessage `json:"report"` // canonical content whose SHA-256 hash is the report hash
+}
+
+type ChainProblem struct {
+	Sequence uint   `json:"sequence,omitempty"`
+	Month    string `json:"month,omitempty"`
+	Revision int    `json:"revision,omitempty"`
+	Problem  string `json:"problem"`
+}
+
+type ChainVerification struct {
+	Valid    bool           `json:"valid"`
+	Reports  int            `json:"reports"`
+	Head     string         `json:"head"`
+	Problems []ChainProblem `json:"problems"`
+}
@@ -0,0 +1,153 @@
+package node_checker
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// YearMonth identifies a calendar month
+type YearMonth struct {
+	Year  int
+	Month time.Month
+}
+
+// parseYearMonth parses month in YYYY-MM format
+func parseYearMonth(value string) (YearMonth, error) {
+	t, err := time.Parse("2006-01", value)
+	if err != nil {
+		return YearMonth{}, errInvalidMonth
+	}
+	return YearMonth{Year: t.Year(), Month: t.Month()}, nil
+}
+
+func (ym YearMonth) String() string {
+	return fmt.Sprintf("%04d-%02d", ym.Year, int(ym.Month))
+}
+
+func (ym YearMonth) next() YearMonth {
+	t := time.Date(ym.Year, ym.Month+1, 1, 0, 0, 0, 0, time.UTC)
+	return YearMonth{Year: t.Year(), Month: t.Month()}
+}
+
+func (ym YearMonth) previous() YearMonth {
+	t := time.Date(ym.Year, ym.Month-1, 1, 0, 0, 0, 0, time.UTC)
+	return YearMonth{Year: t.Year(), Month: t.Month()}
+}
+
+// start returns the first instant of the month in the given location
+func (ym YearMonth) start(location *time.Location) time.Time {
+	return startOfDay(time.Date(ym.Year, ym.Month, 1, 12, 0, 0, 0, location), location)
+}
+
+// end returns the first instant of the following month in the given location
+func (ym YearMonth) end(location *time.Location) time.Time {
+	return ym.next().start(location)
+}
+
+func (ym YearMonth) before(other YearMonth) bool {
+	return ym.Year < other.Year || (ym.Year == other.Year && ym.Month < other.Month)
+}
+
+// rollupDelay is the time waited after the month boundary before the month is closed,
+// so that the last collection run of the month is persisted
+func rollupDelay() time.Duration {
+	if delay := viper.GetDuration("rollup.delay"); delay > 0 {
+		return delay
+	}
+	return 15 * time.Minute
+}
+
+// lastClosableMonth returns the latest month in the location which ended at least rollup delay ago
+func lastClosableMonth(currentTime time.Time, location *time.Location) YearMonth {
+	return monthOf(currentTime.Add(-rollupDelay()), location).previous()
+}
+
+// nextRollupTime returns the time when the current month in the location can be closed
+func nextRollupTime(currentTime time.Time, location *time.Location) time.Time {
+	return monthOf(currentTime.Add(-rollupDelay()), location).end(location).Add(rollupDelay())
+}
+
+// rollupMonth computes monthly uptimes of every node, publishes their report to the chain and marks the month
+// as closed. Month boundaries are cut in the configured reporting timezone.
+func (ns *Service) rollupMonth(month YearMonth) error {
+	location := reportingLocation()
+	if err := ns.createMonthlyUptimes(month, location); err != nil {
+		log.Errorf("Unable to roll up month %v - %v", month, err)
+		return err
+	}
+	if err := ns.publishMonthlyReport(month, location); err != nil {
+		log.Errorf("Unable to publish report of month %v - %v", month, err)
+		return err
+	}
+	closedMonth := ClosedMonth{
+		Year:     month.Year,
+		Month:    int(month.Month),
+		ClosedAt: time.Now(),
+	}
+	return ns.db.saveClosedMonth(&closedMonth)
+}
+
+// rollupMonths recomputes every month in the inclusive range
+func (ns *Service) rollupMonths(from YearMonth, to YearMonth) ([]YearMonth, error) {
+	if to.before(from) {
+		return nil, errInvalidMonth
+	}
+	var done []YearMonth
+	for month := from; !to.before(month); month = month.next() {
+		if err := ns.rollupMonth(month); err != nil {
+			return done, err
+		}
+		done = append(done, month)
+	}
+	return done, nil
+}
+
+// fillMissingMonths closes every month since the first recorded uptime which was not closed yet
+// and publishes reports of closed months which were not published yet
+func (ns *Service) fillMissingMonths(currentTime time.Time) error {
+	first, err := ns.db.findFirstUptimeTime()
+	if err == errCannotLoadDataFromDatabase {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	closedMonths, err := ns.db.findClosedMonths()
+	if err != nil {
+		return err
+	}
+	closed := make(map[YearMonth]bool)
+	for _, closedMonth := range closedMonths {
+		closed[YearMonth{Year: closedMonth.Year, Month: time.Month(closedMonth.Month)}] = true
+	}
+	reports, err := ns.db.findMonthlyReports(false)
+	if err != nil {
+		return err
+	}
+	published := make(map[YearMonth]bool)
+	for _, report := range reports {
+		published[YearMonth{Year: report.Year, Month: time.Month(report.Month)}] = true
+	}
+
+	location := reportingLocation()
+	last := lastClosableMonth(currentTime, location)
+	for month := monthOf(first, location); !last.before(month); month = month.next() {
+		if closed[month] {
+			// months closed before reports were published are published as they are stored
+			if !published[month] {
+				if err := ns.publishMonthlyReport(month, location); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		log.Infof("Month %v was not closed, rolling it up", month)
+		if err := ns.rollupMonth(month); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package node_checker
+
+import (
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Granularities of uptime rollups. Hourly buckets are aligned to UTC hours and daily buckets to midnight in the
+// reporting timezone, so rollups have to be rebuilt after the timezone is changed.
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+)
+
+// maxSeriesPoints limits the number of buckets returned per node
+const maxSeriesPoints = 10000
+
+// granularityStep returns the nominal length of a bucket, days are 23 or 25 hours long across DST transitions
+func granularityStep(granularity string) time.Duration {
+	if granularity == GranularityHour {
+		return time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// rollupGranularities returns granularities maintained after each collection run
+func rollupGranularities() []string {
+	if viper.GetBool("rollups.hourly") {
+		return []string{GranularityDay, GranularityHour}
+	}
+	return []string{GranularityDay}
+}
+
+// bucketStart returns the start of the bucket which contains t, daily buckets start at midnight in the location
+func bucketStart(t time.Time, granularity string, location *time.Location) time.Time {
+	if granularity == GranularityHour {
+		return t.UTC().Truncate(time.Hour)
+	}
+	return startOfDay(t, location).UTC()
+}
+
+// nextBucket returns the start of the bucket which follows the bucket starting at start
+func nextBucket(start time.Time, granularity string, location *time.Location) time.Time {
+	if granularity == GranularityHour {
+		return start.Add(time.Hour)
+	}
+	year, month, day := start.In(location).Date()
+	return startOfDay(time.Date(year, month, day+1, 12, 0, 0, 0, location), location).UTC()
+}
+
+// rollupKey identifies a single rollup bucket of a node
+type rollupKey struct {
+	nodeKey     string
+	granularity string
+	bucket      time.Time
+}
+
+// addIntervalToRollups splits the interval into buckets of every granularity and adds their durations
+func addIntervalToRollups(rollups map[rollupKey]float64, nodeKey string, i interval, granularities []string, location *time.Location) {
+	for _, granularity := range granularities {
+		for start := bucketStart(i.Start, granularity, location); start.Before(i.End); start = nextBucket(start, granularity, location) {
+			part := intersectPeriod([]interval{i}, start, nextBucket(start, granularity, location))
+			if len(part) == 0 {
+				continue
+			}
+			rollups[rollupKey{nodeKey, granularity, start}] += part[0].duration().Seconds()
+		}
+	}
+}
+
+// rollupIncrements returns running time newly covered by the uptime compared to the previously known last uptime
+func rollupIncrements(rollups map[rollupKey]float64, previous Uptime, found bool, uptime Uptime, granularities []string, location *time.Location) {
+	current := uptimeIntervals([]Uptime{uptime})
+	if found {
+		current = subtractIntervals(current, uptimeIntervals([]Uptime{previous}))
+	}
+	for _, i := range current {
+		addIntervalToRollups(rollups, uptime.NodeId, i, granularities, location)
+	}
+}
+
+func rollupRows(rollups map[rollupKey]float64) []UptimeRollup {
+	rows := make([]UptimeRollup, 0, len(rollups))
+	for key, seconds := range rollups {
+		rows = append(rows, UptimeRollup{
+			NodeId:        key.nodeKey,
+			Granularity:   key.granularity,
+			BucketStart:   key.bucket,
+			UptimeSeconds: seconds,
+		})
+	}
+	return rows
+}
+
+// rebuildRollups recomputes rollups of every node within the period from raw uptimes. Rollups before
+// the compaction boundary are kept, as raw uptimes they were computed from were removed.
+func (ns *Service) rebuildRollups(startDate time.Time, endDate time.Time) error {
+	boundary, err := ns.compactionBoundary()
+	if err != nil {
+		return err
+	}
+	granularities := rollupGranularities()
+	location := reportingLocation()
+	startDate = bucketStart(startDate, GranularityDay, location)
+	if last := bucketStart(endDate, GranularityDay, location); last.Before(endDate) {
+		endDate = nextBucket(last, GranularityDay, location)
+	}
+	if startDate.Before(boundary) {
+		startDate = boundary
+	}
+	if !endDate.After(startDate) {
+		return nil
+	}
+	nodes := 0
+	err = ns.db.rebuildUptimeRollups(startDate, endDate, func(uptimes map[string][]Uptime) []UptimeRollup {
+		nodes = len(uptimes)
+		rollups := make(map[rollupKey]float64)
+		for key, nodeUptimes := range uptimes {
+			for _, i := range intersectPeriod(uptimeIntervals(nodeUptimes), startDate, endDate) {
+				addIntervalToRollups(rollups, key, i, granularities, location)
+			}
+		}
+		return rollupRows(rollups)
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("Rebuilt uptime rollups of %v nodes from %v to %v", nodes, startDate, endDate)
+	return nil
+}
+
+// hourAligned tells whether hours in the location start on whole UTC hours at t
+func hourAligned(t time.Time, location *time.Location) bool {
+	_, offset := t.In(location).Zone()
+	return offset%3600 == 0
+}
+
+// getUptimeSeries returns uptime of every node per bucket within the period, buckets without uptime are included.
+// Daily rollups are days in the reporting timezone, so days in another location are summed from hourly rollups,
+// which is possible only when hourly rollups are maintained and the location is offset from UTC by whole hours.
+func (ns *Service) getUptimeSeries(nodeKeys []string, startDate time.Time, endDate time.Time, granularity string, location *time.Location) ([]NodeUptimeSeries, error) {
+	if granularity != GranularityHour && granularity != GranularityDay {
+		return nil, errInvalidGranularity
+	}
+	startDate = bucketStart(startDate, granularity, location)
+	if !endDate.After(startDate) || endDate.Sub(startDate)/granularityStep(granularity) > maxSeriesPoints {
+		return nil, errInvalidSeriesRange
+	}
+
+	source := granularity
+	if location.String() != reportingLocation().String() {
+		source = GranularityHour
+		if granularity == GranularityDay && !viper.GetBool("rollups.hourly") {
+			return nil, errSeriesTimezoneUnavailable
+		}
+	}
+	var buckets []time.Time
+	for start := startDate; start.Before(endDate); start = nextBucket(start, granularity, location) {
+		if source == GranularityHour && !hourAligned(start, location) {
+			return nil, errSeriesTimezoneUnaligned
+		}
+		buckets = append(buckets, start)
+	}
+	last := nextBucket(buckets[len(buckets)-1], granularity, location)
+
+	for i := range nodeKeys {
+		nodeKeys[i] = strings.TrimSpace(nodeKeys[i])
+	}
+	rollups, err := ns.db.findUptimeRollups(nodeKeys, source, startDate, last)
+	if err != nil {
+		return nil, errCannotLoadDataFromDatabase
+	}
+	byNode := make(map[string]map[time.Time]float64)
+	for _, rollup := range rollups {
+		if byNode[rollup.NodeId] == nil {
+			byNode[rollup.NodeId] = make(map[time.Time]float64)
+		}
+		byNode[rollup.NodeId][bucketStart(rollup.BucketStart, granularity, location)] += rollup.UptimeSeconds
+	}
+
+	now := time.Now()
+	var result []NodeUptimeSeries
+	for _, key := range nodeKeys {
+		series := NodeUptimeSeries{Key: key, Granularity: granularity}
+		for _, start := range buckets {
+			end := nextBucket(start, granularity, location)
+			if end.After(now) {
+				end = now
+			}
+			uptime := byNode[key][start]
+			series.Points = append(series.Points, SeriesPoint{
+				Start:      start.In(location),
+				Uptime:     toFixed(uptime, 0),
+				Percentage: percentage(uptime, end.Sub(start).Seconds()),
+			})
+		}
+		result = append(result, series)
+	}
+	return result, nil
+}
+
+type SeriesPoint struct {
+	Start      time.Time `json:"start"`
+	Uptime     float64   `json:"uptime"`
+	Percentage float64   `json:"percentage"`
+}
+
+type NodeUptimeSeries struct {
+	Key         string        `json:"key"`
+	Granularity string        `json:"granularity"`
+	Points      []SeriesPoint `json:"points"`
+}
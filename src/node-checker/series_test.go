@@ -0,0 +1,69 @@
+package node_checker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAddIntervalToRollupsLocalDays(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("timezone database is not available")
+	}
+	// Berlin switches to summer time on 2026-03-29, the day is 23 hours long
+	i := interval{
+		Start: time.Date(2026, time.March, 28, 12, 0, 0, 0, berlin),
+		End:   time.Date(2026, time.March, 30, 12, 0, 0, 0, berlin),
+	}
+	rollups := make(map[rollupKey]float64)
+	addIntervalToRollups(rollups, "node", i, []string{GranularityDay, GranularityHour}, berlin)
+
+	expected := map[time.Time]float64{
+		time.Date(2026, time.March, 28, 0, 0, 0, 0, berlin).UTC(): 12 * 3600,
+		time.Date(2026, time.March, 29, 0, 0, 0, 0, berlin).UTC(): 23 * 3600,
+		time.Date(2026, time.March, 30, 0, 0, 0, 0, berlin).UTC(): 12 * 3600,
+	}
+	var days, hours int
+	var hourly float64
+	for key, seconds := range rollups {
+		if key.granularity == GranularityHour {
+			hours++
+			hourly += seconds
+			continue
+		}
+		days++
+		if expected[key.bucket] != seconds {
+			t.Errorf("expected %v seconds in the day starting at %v, got %v", expected[key.bucket], key.bucket, seconds)
+		}
+	}
+	if days != len(expected) {
+		t.Errorf("expected %v daily buckets, got %v", len(expected), days)
+	}
+	if hours != 47 || hourly != i.duration().Seconds() {
+		t.Errorf("expected 47 hourly buckets covering the interval, got %v with %v seconds", hours, hourly)
+	}
+}
+
+func TestNextBucket(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("timezone database is not available")
+	}
+	tests := []struct {
+		start    time.Time
+		expected time.Duration
+	}{
+		{start: time.Date(2026, time.March, 7, 0, 0, 0, 0, newYork), expected: 24 * time.Hour},
+		{start: time.Date(2026, time.March, 8, 0, 0, 0, 0, newYork), expected: 23 * time.Hour},
+		{start: time.Date(2026, time.November, 1, 0, 0, 0, 0, newYork), expected: 25 * time.Hour},
+	}
+	for _, test := range tests {
+		start := bucketStart(test.start.Add(5*time.Hour), GranularityDay, newYork)
+		if !start.Equal(test.start) {
+			t.Errorf("expected bucket to start at %v, got %v", test.start, start)
+		}
+		if actual := nextBucket(start, GranularityDay, newYork).Sub(start); actual != test.expected {
+			t.Errorf("expected day starting at %v to last %v, got %v", test.start, test.expected, actual)
+		}
+	}
+}
@@ -2,8 +2,6 @@ package node_checker
 
 import (
 	"encoding/json"
-	"io/ioutil"
-	"net/http"
 	"time"
 
 	"math"
@@ -16,22 +14,26 @@ import (
 
 // Service provides access to User related data
 type Service struct {
-	db store
+	db         store
+	sources    *sourceRegistry
+	heartbeats *heartbeatGuard
 }
 
 // DefaultService prepares new instance of Service
 func DefaultService() Service {
-	return NewService(DefaultData())
+	return NewService(DefaultData(), DefaultSources())
 }
 
 // NewService prepares new instance of Service
-func NewService(nodeStore store) Service {
+func NewService(nodeStore store, sources *sourceRegistry) Service {
 	return Service{
-		db: nodeStore,
+		db:         nodeStore,
+		sources:    sources,
+		heartbeats: newHeartbeatGuard(),
 	}
 }
 
-func (ns *Service) exportAllNodesUptimes(startDate time.Time, endDate time.Time) ([]NodeUptimeResponse, error) {
+func (ns *Service) exportAllNodesUptimes(startDate time.Time, endDate time.Time, location *time.Location) ([]NodeUptimeResponse, error) {
 	allNodes, err := ns.db.findNodes()
 	if err != nil {
 		return nil, errCannotFindNodes
@@ -40,42 +42,50 @@ func (ns *Service) exportAllNodesUptimes(startDate time.Time, endDate time.Time)
 	for _, node := range allNodes {
 		allNodeKeys = append(allNodeKeys, node.Key)
 	}
-	response, err := ns.getNodeInfoExport(allNodeKeys, startDate, endDate)
+	response, err := ns.getNodeInfoExport(allNodeKeys, startDate, endDate, location)
 	if err != nil {
 		return nil, err
 	}
 	return response, nil
 }
 
-func (ns *Service) getNodeInfoExport(nodeKeys []string, startDate time.Time, endDate time.Time) ([]NodeUptimeResponse, error) {
-	var exportStart, exportEnd time.Time
-	var currentYear int
-	var thisMonth time.Month
-	currentLocation := exportEnd.Location()
-
+// getNodeInfoExport calculates uptimes for the given period, previous month in the location is used when period is not set
+func (ns *Service) getNodeInfoExport(nodeKeys []string, startDate time.Time, endDate time.Time, location *time.Location) ([]NodeUptimeResponse, error) {
 	if startDate.IsZero() || endDate.IsZero() {
-		currentTime := time.Now()
-		currentYear, thisMonth, _ = currentTime.Date()
-		previousMonth := int(thisMonth)
-		if previousMonth == 1 {
-			previousMonth = 12
-		} else {
-			previousMonth--
-		}
-		previousMonthConverted := time.Month(previousMonth)
-		exportStart = time.Date(currentYear, previousMonthConverted, 1, 0, 0, 0, 0, currentLocation)
-		exportEnd = time.Date(currentYear, thisMonth, 1, 0, 0, 0, 0, currentLocation)
-	} else {
-		exportEnd = endDate
-		exportStart = startDate
+		previousMonth := monthOf(time.Now(), location).previous()
+		startDate = previousMonth.start(location)
+		endDate = previousMonth.end(location)
+	}
+	return ns.calculateUptimes(nodeKeys, startDate, endDate)
+}
+
+// getNodeInfo calculates uptimes from the beginning of the current month in the location until now
+func (ns *Service) getNodeInfo(nodeKeys []string, location *time.Location) ([]NodeUptimeResponse, error) {
+	now := time.Now()
+	return ns.calculateUptimes(nodeKeys, monthOf(now, location).start(location), now)
+}
+
+// calculateUptimes intersects running intervals of every node with the [startDate, endDate) period
+func (ns *Service) calculateUptimes(nodeKeys []string, startDate time.Time, endDate time.Time) ([]NodeUptimeResponse, error) {
+	excused, err := ns.excusedPeriods(startDate, endDate)
+	if err != nil {
+		log.Error("Unable to read excused periods from the db due to error ", err)
+		return nil, errCannotLoadData
+	}
+	adjustments, err := ns.activeAdjustments(startDate, endDate)
+	if err != nil {
+		log.Error("Unable to read uptime adjustments from the db due to error ", err)
+		return nil, errCannotLoadData
+	}
+	boundary, err := ns.compactionBoundary()
+	if err != nil {
+		log.Error("Unable to read uptime compactions from the db due to error ", err)
+		return nil, errCannotLoadData
 	}
 
 	var results []NodeUptimeResponse
 	for _, nodeString := range nodeKeys {
 		nodeString = strings.TrimSpace(nodeString)
-		uptimeSum := 0
-		firstPeriodPastMonth := true
-		lastPeriodActualMonth := Uptime{}
 		dbNode, err := ns.db.findNode(nodeString)
 		if err != nil {
 			if err == errCannotLoadDataFromDatabase {
@@ -85,280 +95,282 @@ func (ns *Service) getNodeInfoExport(nodeKeys []string, startDate time.Time, end
 			log.Error("Unable to read data from the db due to error ", err)
 			return nil, errCannotLoadData
 		}
-		for i := len(dbNode.Uptimes) - 1; i >= 0; i-- {
-			uptime := dbNode.Uptimes[i]
-			if exportEnd.Before(uptime.CreatedAt) {
-				continue
-			} else if uptime.UpdatedAt.After(exportEnd) {
-				uptime.StartTime = int(exportEnd.Sub(uptime.CreatedAt).Seconds())
-			}
-			if exportStart.Before(uptime.CreatedAt) {
-				uptimeSum += uptime.StartTime
-				lastPeriodActualMonth = uptime
-			} else if firstPeriodPastMonth {
-				var actualMonthTime = 0
-				if lastPeriodActualMonth.NodeId != "" {
-					pastMonthPartOfStartTIme := int(exportStart.Sub(uptime.CreatedAt).Seconds())
-					actualMonthTime = uptime.StartTime - pastMonthPartOfStartTIme
-				} else {
-					activeDate := uptime.CreatedAt.Add(time.Second * time.Duration(uptime.StartTime))
-					if activeDate.After(exportStart) {
-						actualMonthTime = int(activeDate.Sub(exportStart).Seconds())
-					}
-				}
-				if actualMonthTime < 0 {
-					actualMonthTime = 0
-				}
-				firstPeriodPastMonth = false
-				uptimeSum = uptimeSum + actualMonthTime
-			} else {
-				break
-			}
-		}
-		floatUptime := float64(uptimeSum)
-		var duration time.Duration
-		duration = exportEnd.Sub(exportStart)
-		durationInSeconds := float64(duration) / float64(time.Second)
-		if floatUptime > durationInSeconds {
-			floatUptime = durationInSeconds
-		}
-		result := NodeUptimeResponse{
-			Key:        nodeString,
-			Uptime:     floatUptime,
-			Downtime:   toFixed(durationInSeconds-floatUptime, 0),
-			Percentage: floatUptime / durationInSeconds * 100,
-			Online:     dbNode.Online,
+		running, err := ns.runningIntervals(dbNode, boundary, startDate)
+		if err != nil {
+			log.Error("Unable to read uptime rollups from the db due to error ", err)
+			return nil, errCannotLoadData
 		}
-		results = append(results, result)
-
+		results = append(results, nodeUptime(dbNode, running, excused.forNode(dbNode.Key), adjustments[dbNode.Key], startDate, endDate))
 	}
 	return results, nil
 }
 
-func (ns *Service) getNodeInfo(nodeKeys []string) ([]NodeUptimeResponse, error) {
-	now := time.Now()
-	currentYear, currentMonth, _ := now.Date()
-	currentLocation := now.Location()
-	firstOfMonth := time.Date(currentYear, currentMonth, 1, 0, 0, 0, 0, currentLocation)
+// nodeUptime calculates uptime of the node from its running intervals within the [startDate, endDate) period.
+// Adjustments are applied on top of the running time, adjusted uptime stays within the observed time.
+func nodeUptime(node Node, running []interval, excusable []interval, adjustments []UptimeAdjustment, startDate time.Time, endDate time.Time) NodeUptimeResponse {
+	floatUptime := toFixed(totalDuration(intersectPeriod(running, startDate, endDate)).Seconds(), 0)
+	excused := toFixed(excusedSeconds(excusable, running, startDate, endDate), 0)
+	observed := endDate.Sub(startDate).Seconds() - excused
+	if floatUptime > observed {
+		floatUptime = observed
+	}
+	rawUptime := floatUptime
+	if len(adjustments) > 0 {
+		floatUptime = math.Max(0, math.Min(observed, toFixed(floatUptime+adjustedSeconds(adjustments, startDate, endDate), 0)))
+	}
+	return NodeUptimeResponse{
+		Key:         node.Key,
+		Uptime:      floatUptime,
+		Downtime:    toFixed(observed-floatUptime, 0),
+		Percentage:  percentage(floatUptime, observed),
+		Excused:     excused,
+		Adjusted:    floatUptime - rawUptime,
+		Adjustments: adjustments,
+		Online:      node.Online,
+	}
+}
 
-	var results []NodeUptimeResponse
-	for _, nodeString := range nodeKeys {
-		nodeString = strings.TrimSpace(nodeString)
-		uptimeSum := 0
-		firstPeriodPastMonth := true
-		lastPeriodActualMonth := Uptime{}
-		dbNode, err := ns.db.findNode(nodeString)
-		if err != nil {
-			if err == errCannotLoadDataFromDatabase {
-				log.Warn("Missing records for node ", nodeString)
-				continue
-			}
-			log.Error("Unable to read data from the db due to error ", err)
-			return nil, errCannotLoadData
-		}
-		for i := len(dbNode.Uptimes) - 1; i >= 0; i-- {
-			uptime := dbNode.Uptimes[i]
-			if firstOfMonth.Before(uptime.CreatedAt) {
-				uptimeSum += uptime.StartTime
-				lastPeriodActualMonth = uptime
-			} else if firstPeriodPastMonth {
-				var actualMonthTime = 0
-				if lastPeriodActualMonth.NodeId != "" {
-					var downtimeSum = 0
-					secondsBetweenPeriods := int(lastPeriodActualMonth.CreatedAt.Sub(uptime.CreatedAt).Seconds())
-					if secondsBetweenPeriods > uptime.StartTime {
-						downtimeSum = secondsBetweenPeriods - uptime.StartTime
-					}
-					actualMonthTime = int(lastPeriodActualMonth.CreatedAt.Sub(firstOfMonth).Seconds()) - downtimeSum
-
-				} else {
-					activeDate := uptime.CreatedAt.Add(time.Second * time.Duration(uptime.StartTime))
-					if activeDate.After(firstOfMonth) {
-						actualMonthTime = int(activeDate.Sub(firstOfMonth).Seconds())
-					}
-				}
-				if actualMonthTime < 0 {
-					actualMonthTime = 0
-				}
-				firstPeriodPastMonth = false
-				uptimeSum = uptimeSum + actualMonthTime
-			}
-		}
+// updateNodeInfo runs a single collection and returns its persisted report
+func (ns *Service) updateNodeInfo() (CollectionRun, error) {
+	run := CollectionRun{StartedAt: time.Now()}
+	ns.startCollectionRun(&run)
+	err := ns.collect(&run)
+	run.FinishedAt = time.Now()
+	ns.finishCollectionRun(&run, err)
+	return run, err
+}
 
-		floatUptime := float64(uptimeSum)
-		duration := time.Since(firstOfMonth)
-		durationInSeconds := float64(duration) / float64(time.Second)
-		if floatUptime > durationInSeconds {
-			floatUptime = durationInSeconds
-		}
-		result := NodeUptimeResponse{
-			Key:        nodeString,
-			Uptime:     floatUptime,
-			Downtime:   toFixed(durationInSeconds-floatUptime, 0),
-			Percentage: floatUptime / durationInSeconds * 100,
-			Online:     dbNode.Online,
-		}
-		results = append(results, result)
+// startCollectionRun stores the run before collecting so that events can reference it
+func (ns *Service) startCollectionRun(run *CollectionRun) {
+	run.FinishedAt = run.StartedAt
+	if err := ns.db.createCollectionRun(run); err != nil {
+		log.Error("Unable to store collection run report due to error ", err)
+	}
+}
+
+func (ns *Service) finishCollectionRun(run *CollectionRun, err error) {
+	if err != nil {
+		run.Error = err.Error()
+	}
+	if run.Id == 0 {
+		return
+	}
+	if dbErr := ns.db.updateCollectionRun(run); dbErr != nil {
+		log.Error("Unable to store collection run report due to error ", dbErr)
 	}
-	return results, nil
 }
 
-func (ns *Service) updateNodeInfo() error {
+func (ns *Service) collect(run *CollectionRun) error {
 	start := time.Now()
 	log.Info("Starting update process for nodes uptime")
-	uptimeThreshold := int(viper.GetDuration("server.uptime-threshold").Seconds())
-	res, err := getDataFromAPI()
+	res, sourceNames, fetchedAt, err := ns.sources.fetchAll()
+	run.HttpStatus = ns.sources.primaryStatus()
 	if err != nil {
-		log.Error("Unable to fetch the data from the external API")
+		log.Error("Unable to fetch the data from any of the node sources")
+		ns.recordFailedRun(fetchedAt)
 		return err
 	}
+	run.Source = strings.Join(sourceNames, ",")
 
 	elapsed := time.Since(start)
 	log.Infof("Pulled data from %v", elapsed)
-	currentTime := time.Now()
-	var totalTime time.Duration
-	nodes, err := ns.db.findNodes()
-	if err != nil && err != errCannotLoadDataFromDatabase {
-		return err
+	// snapshots are archived with the same time, so that a rebuild applies them exactly the same way
+	return ns.applyCollection(*res, fetchedAt, run)
+}
+
+// applyCollection persists nodes received in a single run as seen at currentTime
+func (ns *Service) applyCollection(res NodeResponse, currentTime time.Time, run *CollectionRun) error {
+	ns.detectCollectorGap(currentTime)
+	return ns.persistNodes(res, currentTime, run, false)
+}
+
+// maxCollectionAttempts limits how many times a batch is computed again after a concurrent write
+const maxCollectionAttempts = 3
+
+// persistNodes writes nodes seen at currentTime in a single batch, either received in a collection run or pushed
+// by a heartbeat. The batch is computed again when a concurrent write changed uptimes it was computed from.
+func (ns *Service) persistNodes(res NodeResponse, currentTime time.Time, run *CollectionRun, heartbeat bool) error {
+	var err error
+	for attempt := 1; attempt <= maxCollectionAttempts; attempt++ {
+		counts := *run
+		if err = ns.saveNodes(res, currentTime, &counts, heartbeat); err != errCollectionConflict {
+			*run = counts
+			return err
+		}
+		log.Warnf("Uptimes were changed by a concurrent write, persisting %v nodes again", len(res))
+	}
+	return err
+}
+
+func (ns *Service) saveNodes(res NodeResponse, currentTime time.Time, run *CollectionRun, heartbeat bool) error {
+	uptimeThreshold := int(viper.GetDuration("server.uptime-threshold").Seconds())
+	run.NodesReceived = len(res)
+	batch := collectionBatch{CheckTime: currentTime, Heartbeat: heartbeat}
+	if run.Id != 0 {
+		batch.RunId = &run.Id
 	}
-	for _, resUptime := range *res {
+	var nodeKeys []string
+	for _, resUptime := range res {
 		if resUptime.StartTime > uptimeThreshold { // skipping records smaller than configured threshold
-			dbNode, err := findNode(resUptime.Key, nodes)
-			if err != nil {
-				ns.createNewNode(resUptime, currentTime)
-			} else {
-				totalTime += ns.updateNode(dbNode, resUptime, currentTime)
-			}
+			nodeKeys = append(nodeKeys, resUptime.Key)
 		}
 	}
+	run.NodesSkipped = run.NodesReceived - len(nodeKeys)
 
-	log.Infof("Total time reading from db %v", totalTime)
-	ns.db.updateAllNodesOnlineStatus(currentTime)
-
-	log.Info("Done with updating")
-	return nil
-}
-
-func (ns *Service) updateNode(node Node, def NodeDef, currentTime time.Time) time.Duration {
-	err := ns.db.updateNodeOnlineStatus(&node, true, currentTime)
+	start := time.Now()
+	lastUptimes, err := ns.db.findLastUptimes(nodeKeys)
 	if err != nil {
-		log.Debug("Error updating online status for node with key: ", node.Key)
+		return err
+	}
+	batch.LastUptimes = lastUptimes
+	currentMetadata, err := ns.db.findCurrentMetadata(nodeKeys)
+	if err != nil {
+		return err
 	}
+	offlineNodes, err := ns.db.findOfflineNodes(nodeKeys)
+	if err != nil {
+		return err
+	}
+	ignoredAttributes := ignoredMetadata()
+	granularities := rollupGranularities()
+	location := reportingLocation()
+	rollups := make(map[rollupKey]float64)
+	log.Infof("Preloaded last uptimes for %v nodes in %v", len(nodeKeys), time.Since(start))
+
+	for _, resUptime := range res {
+		if resUptime.StartTime <= uptimeThreshold {
+			continue
+		}
+		lastUptime, found := lastUptimes[resUptime.Key]
+		if heartbeat && staleHeartbeat(lastUptime, found, resUptime, currentTime) {
+			log.Debugf("Ignored heartbeat of node %v which started before its last uptime", resUptime.Key)
+			run.NodesSkipped++
+			continue
+		}
+		uptime, isNew := nextUptime(lastUptime, found, resUptime, currentTime)
+		batch.OnlineNodes = append(batch.OnlineNodes, resUptime.Key)
+		if isNew {
+			batch.NewUptimes = append(batch.NewUptimes, uptime)
+		} else {
+			batch.ExtendedUptimes = append(batch.ExtendedUptimes, uptime)
+		}
+		if !found {
+			run.NewNodes++
+		} else if isNew {
+			run.Restarts++
+		}
+		rollupIncrements(rollups, lastUptime, found, uptime, granularities, location)
+		batch.Events = append(batch.Events, collectionEvents(resUptime.Key, found, offlineNodes[resUptime.Key], uptime, isNew, currentTime, batch.RunId)...)
 
-	start := time.Now()
-	lastUptime, err := ns.db.getLastUptimeForNode(node.Key)
+		if heartbeat {
+			continue // heartbeats do not carry metadata
+		}
+		closed, created := diffMetadata(resUptime.Key, resUptime.Metadata, currentMetadata[resUptime.Key], ignoredAttributes, currentTime)
+		batch.ClosedMetadata = append(batch.ClosedMetadata, closed...)
+		batch.NewMetadata = append(batch.NewMetadata, created...)
+	}
 
-	elapsed := time.Since(start)
+	batch.Rollups = rollupRows(rollups)
+	batch.NewNodes = run.NewNodes
+	batch.Restarts = run.Restarts
 
-	createNewRecord := false
-	if err != nil {
-		if err != errCannotLoadDataFromDatabase {
-			log.Errorf("Unable to find uptime for node %v due to error %v", node.Key, err)
+	start = time.Now()
+	if err := ns.db.saveCollection(&batch); err != nil {
+		if err != errCollectionConflict {
+			log.Error("Unable to persist collected uptimes due to error ", err)
 		}
+		return err
+	}
+	run.NodesMarkedOffline = int(batch.MarkedOffline)
+	log.Infof("Persisted %v nodes, %v new and %v extended uptimes in %v", len(batch.OnlineNodes), len(batch.NewUptimes), len(batch.ExtendedUptimes), time.Since(start))
 
-		// We just don't have current node in db yet
-		createNewRecord = true
+	log.Info("Done with updating")
+	return nil
+}
+
+func (ns *Service) getCollectionRuns(page int, pageSize int) (CollectionRunsPage, error) {
+	runs, total, err := ns.db.findCollectionRuns(page, pageSize)
+	if err != nil {
+		return CollectionRunsPage{}, errCannotLoadDataFromDatabase
 	}
+	return CollectionRunsPage{
+		Runs:     runs,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+func (ns *Service) getCollectionRun(id uint) (CollectionRun, error) {
+	return ns.db.findCollectionRun(id)
+}
 
+// nextUptime decides whether reported running time extends the last known uptime or starts a new one
+func nextUptime(lastUptime Uptime, found bool, def NodeDef, currentTime time.Time) (Uptime, bool) {
 	//creating new record if we:
-	createNewRecord = createNewRecord || // have not found one in DB, or
+	createNewRecord := !found || // have not found one in DB (we just don't have current node in db yet), or
 		def.StartTime <= lastUptime.StartTime || // new running time is smaller than previously recorded, or
 		lastUptime.CreatedAt.Add(time.Duration(def.StartTime+UptimeDifferenceOffsetInSeconds)*time.Second).Before(currentTime) // dealing with old record we should leave as is in past
 
 	if createNewRecord {
 		// if running time is less or equal than before means that node was restarted
-		newUptime := Uptime{
-			NodeId:    node.Key,
+		return Uptime{
+			NodeId:    def.Key,
 			StartTime: def.StartTime,
 			CreatedAt: currentTime.Add(time.Duration(-def.StartTime) * time.Second),
-		}
-		err := ns.db.createUptime(&newUptime)
-		if err != nil {
-			log.Info("Error creating new uptime for node with key: ", node.Key)
-		}
-	} else {
-		// if running time increased means that same uptime should be kept
-		lastUptime.StartTime = def.StartTime
-		ns.db.updateUptime(&lastUptime)
-	}
-	return elapsed
-}
-
-func (ns *Service) createNewNode(def NodeDef, currentTime time.Time) {
-	uptime := Uptime{
-		NodeId:    def.Key,
-		StartTime: def.StartTime,
-		CreatedAt: currentTime.Add(time.Duration(-def.StartTime) * time.Second),
-	}
-	node := Node{
-		Key:       def.Key,
-		LastCheck: currentTime,
-		Uptimes:   []Uptime{uptime},
-		Online:    true,
-	}
-
-	if err := ns.db.createNode(&node); err != nil {
-		log.Errorf("Unable to create a new node %v from received data %v", node, def)
-		return
+		}, true
 	}
+	// if running time increased means that same uptime should be kept
+	lastUptime.StartTime = def.StartTime
+	return lastUptime, false
 }
 
-func (ns *Service) createUptimesForPastMonths(nodeKey string, month int, year int, startTime int, percentage float64, downtime int) error {
-	monthlyUptime := MonthlyUptime{
-		NodeId:         nodeKey,
-		Month:          month,
-		Year:           year,
-		TotalStartTime: startTime,
-		Percentage:     percentage,
-		Downtime:       downtime,
-	}
-	lastUptime, err := ns.db.getLastUptimeForNode(nodeKey)
+// createMonthlyUptimes replaces monthly uptimes of the given month in the location with one row for every node
+// which was online during it
+func (ns *Service) createMonthlyUptimes(ym YearMonth, location *time.Location) error {
+	details, err := ns.exportAllNodesUptimes(ym.start(location), ym.end(location), location)
 	if err != nil {
-		log.Error("Cannot get last uptime for node with key %v", nodeKey)
-	}
-	monthlyUptime.LastStartTime = lastUptime.StartTime
-	erro := ns.db.createMonthlyUptime(&monthlyUptime)
-	if erro != nil {
-		log.Error("Cannot create uptimes for past months", err)
-	} else {
-		log.Info("Monthly uptime with key %v is created for %v. month", nodeKey, month)
+		return err
 	}
-	return nil
-}
-
-func (ns *Service) getNodes() ([]Node, error) {
-	nodes, err := ns.db.findNodes()
-	if err != nil {
-		log.Fatal("Cannot fetch nodes from db", err)
+	keys := make([]string, 0, len(details))
+	for _, detail := range details {
+		if detail.Uptime != 0 {
+			keys = append(keys, detail.Key)
+		}
 	}
-	return nodes, nil
-}
-
-func (ns *Service) getNode(nodeKey string) (Node, error) {
-	node, err := ns.db.findNode(nodeKey)
+	lastUptimes, err := ns.db.findLastUptimes(keys)
 	if err != nil {
-		log.Error("Cannot find node with %v key ", nodeKey)
+		log.Errorf("Cannot get last uptimes of nodes for %v - %v", ym, err)
+		return err
 	}
-	return node, nil
-}
 
-func getDataFromAPI() (*NodeResponse, error) {
-	var apiString = viper.GetString("server.node-check-api")
-	response, err := http.Get(apiString)
-	if err != nil {
-		return nil, errCannotLoadData
+	monthlyUptimes := make([]MonthlyUptime, 0, len(keys))
+	for _, detail := range details {
+		if detail.Uptime == 0 {
+			continue
+		}
+		monthlyUptimes = append(monthlyUptimes, MonthlyUptime{
+			NodeId:         detail.Key,
+			Month:          int(ym.Month),
+			Year:           ym.Year,
+			TotalStartTime: int(detail.Uptime),
+			Percentage:     detail.Percentage,
+			Downtime:       int(detail.Downtime),
+			Excused:        int(detail.Excused),
+			Adjusted:       int(detail.Adjusted),
+			LastStartTime:  lastUptimes[detail.Key].StartTime,
+		})
 	}
-	defer response.Body.Close()
-	contents, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return nil, errCannotLoadData
+	if err := ns.db.replaceMonthlyUptimes(ym.Year, int(ym.Month), monthlyUptimes); err != nil {
+		log.Errorf("Cannot create monthly uptimes for %v - %v", ym, err)
+		return err
 	}
+	log.Infof("%v monthly uptimes created for %v in %v", len(monthlyUptimes), ym, location)
+	return nil
+}
 
-	uptimes, err := extractUptimesFromURL(contents)
-	if err != nil {
-		return nil, err
-	}
-	return uptimes, nil
+func (ns *Service) getSourcesHealth() []SourceHealth {
+	return ns.sources.health()
 }
 
 func extractUptimesFromURL(body []byte) (*NodeResponse, error) {
@@ -370,35 +382,17 @@ func extractUptimesFromURL(body []byte) (*NodeResponse, error) {
 	return s, err
 }
 
-func findNode(key string, allNodes []Node) (Node, error) {
-	index := 0
-	if len(allNodes) == 0 {
-		return Node{}, errCannotFindNodeWithKey
-	}
-	if allNodes[index].Key > key {
-		return Node{}, errCannotFindNodeWithKey
-	}
-	low, mid, high := 0, 0, len(allNodes)
-	if allNodes[low].Key == key {
-		return allNodes[low], nil
-	}
-	for (low + 1) != high {
-		mid = low + (high-low)/2
-		if allNodes[mid].Key == key {
-			return allNodes[mid], nil
-		} else if allNodes[mid].Key < key {
-			low = mid
-		} else {
-			high = mid
-		}
-	}
-	return Node{}, errCannotFindNodeWithKey
-}
-
 func round(num float64) int {
 	return int(num + math.Copysign(0.5, num))
 }
 
+func percentage(uptime float64, duration float64) float64 {
+	if duration <= 0 {
+		return 0
+	}
+	return uptime / duration * 100
+}
+
 func toFixed(num float64, precision int) float64 {
 	output := math.Pow(10, float64(precision))
 	return float64(round(num*output)) / output
@@ -411,19 +405,25 @@ var UptimeDifferenceOffsetInSeconds = 200
 type NodeResponse []NodeDef
 
 type NodeDef struct {
-	Key       string `json:"key"`
-	StartTime int    `json:"start_time"`
+	Key       string            `json:"key"`
+	StartTime int               `json:"start_time"`
+	Metadata  map[string]string `json:"metadata,omitempty"` // every other attribute reported by discovery
 }
 
 type NodeUptimeResponse struct {
-	Key        string
-	Uptime     float64
-	Downtime   float64
-	Percentage float64
-	Online     bool
+	Key         string
+	Uptime      float64
+	Downtime    float64
+	Percentage  float64
+	Excused     float64
+	Adjusted    float64            // seconds added to uptime by adjustments, negative when taken
+	Adjustments []UptimeAdjustment `json:",omitempty"`
+	Online      bool
 }
 
-type UptimeAndMonthlyUptimeDifference struct {
-	NodeId     string
-	Difference int
+type CollectionRunsPage struct {
+	Runs     []CollectionRun `json:"runs"`
+	Total    int             `json:"total"`
+	Page     int             `json:"page"`
+	PageSize int             `json:"pageSize"`
 }
@@ -0,0 +1,92 @@
+package node_checker
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/spf13/viper"
+)
+
+// Response headers carrying the detached signature of a signed export
+const (
+	SignatureHeader          = "X-Signature"            // hex encoded signature of SHA-256 hash of the response body
+	SignaturePublicKeyHeader = "X-Signature-Public-Key" // hex encoded public key of the service
+	SignatureHashHeader      = "X-Signature-Hash"       // hex encoded SHA-256 hash of the response body
+)
+
+// signingKey returns the secret key configured for signing exports, exports are not signed when it is not set
+func signingKey() (cipher.SecKey, bool, error) {
+	value := strings.TrimSpace(viper.GetString("signing.secret-key"))
+	if value == "" {
+		return cipher.SecKey{}, false, nil
+	}
+	secKey, err := cipher.SecKeyFromHex(value)
+	if err != nil || secKey.Verify() != nil {
+		return cipher.SecKey{}, false, errInvalidSigningKey
+	}
+	return secKey, true, nil
+}
+
+// canonicalExport encodes the export as compact JSON, the encoded bytes are what is signed and sent
+func canonicalExport(export interface{}) ([]byte, error) {
+	return json.Marshal(export)
+}
+
+// signExport signs SHA-256 hash of the content, nil is returned when signing is not configured
+func signExport(content []byte) (*ExportSignature, error) {
+	secKey, found, err := signingKey()
+	if err != nil || !found {
+		return nil, err
+	}
+	hash := cipher.SumSHA256(content)
+	return &ExportSignature{
+		Hash:      hash.Hex(),
+		Signature: cipher.SignHash(hash, secKey).Hex(),
+		PublicKey: cipher.PubKeyFromSecKey(secKey).Hex(),
+	}, nil
+}
+
+// getSigningKey returns the public key and skycoin address of the key signing exports
+func (ns *Service) getSigningKey() (SigningKeyResponse, error) {
+	secKey, found, err := signingKey()
+	if err != nil {
+		return SigningKeyResponse{}, err
+	}
+	if !found {
+		return SigningKeyResponse{}, errSigningNotConfigured
+	}
+	pubKey := cipher.PubKeyFromSecKey(secKey)
+	return SigningKeyResponse{
+		PublicKey: pubKey.Hex(),
+		Address:   cipher.AddressFromPubKey(pubKey).String(),
+	}, nil
+}
+
+// VerifyExport checks that the signature of SHA-256 hash of the exported content was made by the secret key
+// of the public key. Content has to be the exact response body of the export.
+func VerifyExport(content []byte, signature string, publicKey string) error {
+	pubKey, err := cipher.PubKeyFromHex(strings.TrimSpace(publicKey))
+	if err != nil {
+		return errInvalidPublicKey
+	}
+	sig, err := cipher.SigFromHex(strings.TrimSpace(signature))
+	if err != nil {
+		return errInvalidExportSignature
+	}
+	if err := cipher.VerifySignature(pubKey, sig, cipher.SumSHA256(content)); err != nil {
+		return errInvalidExportSignature
+	}
+	return nil
+}
+
+type ExportSignature struct {
+	Hash      string `json:"hash"`
+	Signature string `json:"signature"`
+	PublicKey string `json:"publicKey"`
+}
+
+type SigningKeyResponse struct {
+	PublicKey string `json:"publicKey"`
+	Address   string `json:"address"`
+}
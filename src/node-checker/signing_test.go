@@ -0,0 +1,56 @@
+package node_checker
+
+import (
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/spf13/viper"
+)
+
+func setSigningKey(t *testing.T, value string) {
+	viper.Set("signing.secret-key", value)
+	t.Cleanup(func() { viper.Set("signing.secret-key", "") })
+}
+
+func TestSignExport(t *testing.T) {
+	pubKey, secKey := cipher.GenerateKeyPair()
+	setSigningKey(t, secKey.Hex())
+	content := []byte(`{"key":"node","uptime":86400}`)
+
+	signature, err := signExport(content)
+	if err != nil || signature == nil {
+		t.Fatalf("expected a signature, got %v and error %v", signature, err)
+	}
+	if signature.PublicKey != pubKey.Hex() || signature.Hash != cipher.SumSHA256(content).Hex() {
+		t.Errorf("unexpected signature %+v", signature)
+	}
+	if err := VerifyExport(content, signature.Signature, signature.PublicKey); err != nil {
+		t.Errorf("expected the export to verify, got %v", err)
+	}
+	if err := VerifyExport([]byte(`{"key":"node","uptime":86401}`), signature.Signature, signature.PublicKey); err != errInvalidExportSignature {
+		t.Errorf("expected modified body to be rejected, got %v", err)
+	}
+	otherKey, _ := cipher.GenerateKeyPair()
+	if err := VerifyExport(content, signature.Signature, otherKey.Hex()); err != errInvalidExportSignature {
+		t.Errorf("expected wrong public key to be rejected, got %v", err)
+	}
+	if err := VerifyExport(content, signature.Signature, "not a key"); err != errInvalidPublicKey {
+		t.Errorf("expected malformed public key to be rejected, got %v", err)
+	}
+	if err := VerifyExport(content, "not a signature", signature.PublicKey); err != errInvalidExportSignature {
+		t.Errorf("expected malformed signature to be rejected, got %v", err)
+	}
+}
+
+func TestSigningKeyConfiguration(t *testing.T) {
+	setSigningKey(t, "")
+	if signature, err := signExport([]byte("{}")); signature != nil || err != nil {
+		t.Errorf("expected exports not to be signed without key, got %v and error %v", signature, err)
+	}
+	for _, value := range []string{"abc", "00", "0000000000000000000000000000000000000000000000000000000000000000"} {
+		setSigningKey(t, value)
+		if _, _, err := signingKey(); err != errInvalidSigningKey {
+			t.Errorf("%q: expected %v, got %v", value, errInvalidSigningKey, err)
+		}
+	}
+}
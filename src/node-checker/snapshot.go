@@ -0,0 +1,155 @@
+package node_checker
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const snapshotExtension = ".json.gz"
+
+// Sources of snapshots which are not payloads received from node sources
+const (
+	snapshotFailedRun = "@failed"    // empty snapshot of a run in which no node source responded
+	snapshotHeartbeat = "@heartbeat" // node pushed by a heartbeat
+)
+
+// snapshot is a single archived payload received from a node source or pushed by a heartbeat
+type snapshot struct {
+	Path    string
+	Source  string
+	TakenAt time.Time
+}
+
+// snapshotArchive stores raw payloads received from node sources as compressed files on local disk
+type snapshotArchive struct {
+	dir       string
+	retention time.Duration
+}
+
+// DefaultSnapshots prepares archive configured in snapshots section, nil is returned when archiving is disabled
+func DefaultSnapshots() *snapshotArchive {
+	if !viper.GetBool("snapshots.enabled") {
+		return nil
+	}
+	return NewSnapshots(viper.GetString("snapshots.dir"), viper.GetDuration("snapshots.retention"))
+}
+
+// NewSnapshots prepares archive in the given directory. Zero retention keeps snapshots forever.
+func NewSnapshots(dir string, retention time.Duration) *snapshotArchive {
+	if dir == "" {
+		dir = "snapshots"
+	}
+	return &snapshotArchive{
+		dir:       dir,
+		retention: retention,
+	}
+}
+
+// save writes the payload received from the source, takenAt is the time at which it was applied
+func (a *snapshotArchive) save(source string, takenAt time.Time, payload []byte) error {
+	if err := os.MkdirAll(a.dir, 0755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%019d_%s%s", takenAt.UnixNano(), source, snapshotExtension)
+	tmpPath := filepath.Join(a.dir, "."+name)
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	writer := gzip.NewWriter(file)
+	if _, err := writer.Write(payload); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, filepath.Join(a.dir, name))
+}
+
+// list returns all archived snapshots ordered by time
+func (a *snapshotArchive) list() ([]snapshot, error) {
+	files, err := ioutil.ReadDir(a.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var snapshots []snapshot
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, snapshotExtension) || strings.HasPrefix(name, ".") {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimSuffix(name, snapshotExtension), "_", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		nanos, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, snapshot{
+			Path:    filepath.Join(a.dir, name),
+			Source:  parts[1],
+			TakenAt: time.Unix(0, nanos),
+		})
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].TakenAt.Before(snapshots[j].TakenAt) })
+	return snapshots, nil
+}
+
+// read returns decompressed payload of the snapshot
+func (a *snapshotArchive) read(s snapshot) ([]byte, error) {
+	file, err := os.Open(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+// prune removes snapshots older than the configured retention
+func (a *snapshotArchive) prune(currentTime time.Time) {
+	if a.retention <= 0 {
+		return
+	}
+	snapshots, err := a.list()
+	if err != nil {
+		log.Errorf("Unable to list snapshots in %v - %v", a.dir, err)
+		return
+	}
+	limit := currentTime.Add(-a.retention)
+	for _, s := range snapshots {
+		if !s.TakenAt.Before(limit) {
+			break
+		}
+		if err := os.Remove(s.Path); err != nil {
+			log.Errorf("Unable to remove snapshot %v - %v", s.Path, err)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package node_checker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Merge policies used to resolve conflicts when several sources report the same node
+const (
+	MergeMaxStartTime = "max-start-time"
+	MergePrimaryWins  = "primary-wins"
+	MergeQuorum       = "quorum"
+)
+
+func validMergePolicy(policy string) bool {
+	return policy == MergeMaxStartTime || policy == MergePrimaryWins || policy == MergeQuorum
+}
+
+// NodeSource is a discovery endpoint that provides the list of currently running nodes
+type NodeSource interface {
+	Name() string
+	Fetch() (NodeResponse, []byte, error)
+	Health() SourceHealth
+}
+
+// SourceHealth describes the outcome of the recent fetches from a single source
+type SourceHealth struct {
+	Name                string    `json:"name"`
+	URL                 string    `json:"url"`
+	Healthy             bool      `json:"healthy"`
+	LastStatus          int       `json:"lastStatus"`
+	LastNodeCount       int       `json:"lastNodeCount"`
+	LastSuccess         time.Time `json:"lastSuccess"`
+	LastFailure         time.Time `json:"lastFailure"`
+	LastError           string    `json:"lastError,omitempty"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+}
+
+// sourceConfig is a single entry of the sources.endpoints configuration list
+type sourceConfig struct {
+	Name    string        `mapstructure:"name"`
+	URL     string        `mapstructure:"url"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// httpNodeSource implements NodeSource by polling discovery getAll endpoint
+type httpNodeSource struct {
+	name   string
+	url    string
+	client *http.Client
+
+	mux    sync.RWMutex
+	health SourceHealth
+}
+
+func newHTTPNodeSource(name, url string, timeout time.Duration) *httpNodeSource {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &httpNodeSource{
+		name:   name,
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+		health: SourceHealth{Name: name, URL: url},
+	}
+}
+
+func (s *httpNodeSource) Name() string {
+	return s.name
+}
+
+func (s *httpNodeSource) Fetch() (NodeResponse, []byte, error) {
+	response, err := s.client.Get(s.url)
+	if err != nil {
+		s.recordFailure(0, err)
+		return nil, nil, errCannotLoadData
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		s.recordFailure(response.StatusCode, fmt.Errorf("unexpected status %v", response.Status))
+		return nil, nil, errCannotLoadData
+	}
+	contents, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		s.recordFailure(response.StatusCode, err)
+		return nil, nil, errCannotLoadData
+	}
+
+	uptimes, err := extractUptimesFromURL(contents)
+	if err != nil {
+		s.recordFailure(response.StatusCode, err)
+		return nil, nil, err
+	}
+	s.recordSuccess(response.StatusCode, len(*uptimes))
+	return *uptimes, contents, nil
+}
+
+func (s *httpNodeSource) Health() SourceHealth {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	return s.health
+}
+
+func (s *httpNodeSource) recordSuccess(status, nodeCount int) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.health.Healthy = true
+	s.health.LastStatus = status
+	s.health.LastNodeCount = nodeCount
+	s.health.LastSuccess = time.Now()
+	s.health.LastError = ""
+	s.health.ConsecutiveFailures = 0
+}
+
+func (s *httpNodeSource) recordFailure(status int, err error) {
+	log.Errorf("Unable to fetch nodes from source %v (%v) - %v", s.name, s.url, err)
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.health.Healthy = false
+	s.health.LastStatus = status
+	s.health.LastFailure = time.Now()
+	s.health.LastError = err.Error()
+	s.health.ConsecutiveFailures++
+}
+
+// sourceRegistry holds all configured sources, ordered by priority, and merges their results
+type sourceRegistry struct {
+	sources []NodeSource
+	policy  string
+	quorum  int
+	archive *snapshotArchive
+}
+
+// DefaultSources prepares registry of sources defined in the configuration.
+// When no sources are configured server.node-check-api is used as the only one.
+func DefaultSources() *sourceRegistry {
+	var configs []sourceConfig
+	if err := viper.UnmarshalKey("sources.endpoints", &configs); err != nil {
+		log.Errorf("Unable to read node sources from configuration - %v", err)
+	}
+
+	var sources []NodeSource
+	for i, cfg := range configs {
+		if cfg.URL == "" {
+			log.Warnf("Skipping node source %v without url", cfg.Name)
+			continue
+		}
+		if cfg.Name == "" {
+			cfg.Name = fmt.Sprintf("source-%v", i+1)
+		}
+		sources = append(sources, newHTTPNodeSource(cfg.Name, cfg.URL, cfg.Timeout))
+	}
+	if len(sources) == 0 {
+		sources = append(sources, newHTTPNodeSource("default", viper.GetString("server.node-check-api"), 0))
+	}
+
+	policy := viper.GetString("sources.merge-policy")
+	if policy != "" && !validMergePolicy(policy) {
+		log.Fatalf("Unknown merge policy %q, expected %v, %v or %v", policy, MergeMaxStartTime, MergePrimaryWins, MergeQuorum)
+	}
+	registry := NewSources(policy, viper.GetInt("sources.quorum"), sources...)
+	registry.archive = DefaultSnapshots()
+	return registry
+}
+
+// NewSources prepares registry of sources. First source has the highest priority. Quorum is the number of
+// sources which have to report a node under the quorum policy, zero stands for the majority of sources
+// which responded in the run.
+func NewSources(policy string, quorum int, sources ...NodeSource) *sourceRegistry {
+	if policy == "" {
+		policy = MergeMaxStartTime
+	}
+	if quorum < 0 {
+		quorum = 0
+	}
+	return &sourceRegistry{
+		sources: sources,
+		policy:  policy,
+		quorum:  quorum,
+	}
+}
+
+// fetchAll pulls nodes from every source concurrently and returns the merged view with names of
+// sources which responded and the time at which all of them finished, which is the time nodes were seen at.
+// Error is returned only when none of the sources responded. Received payloads are archived as snapshots
+// taken at that time, a run in which no source responded is archived as an empty failed run snapshot.
+func (r *sourceRegistry) fetchAll() (*NodeResponse, []string, time.Time, error) {
+	if len(r.sources) == 0 {
+		return nil, nil, time.Now(), errNoNodeSources
+	}
+
+	results := make([]NodeResponse, len(r.sources))
+	payloads := make([][]byte, len(r.sources))
+	errs := make([]error, len(r.sources))
+	var wg sync.WaitGroup
+	for i, source := range r.sources {
+		wg.Add(1)
+		go func(i int, source NodeSource) {
+			defer wg.Done()
+			results[i], payloads[i], errs[i] = source.Fetch()
+		}(i, source)
+	}
+	wg.Wait()
+	fetchedAt := time.Now()
+
+	var (
+		responded []NodeResponse
+		names     []string
+	)
+	for i, source := range r.sources {
+		if errs[i] != nil {
+			log.Warnf("Node source %v is not available in this run", source.Name())
+			continue
+		}
+		responded = append(responded, results[i])
+		names = append(names, source.Name())
+		if r.archive != nil && payloads[i] != nil {
+			if err := r.archive.save(source.Name(), fetchedAt, payloads[i]); err != nil {
+				log.Errorf("Unable to archive snapshot from source %v - %v", source.Name(), err)
+			}
+		}
+	}
+	if r.archive != nil {
+		if len(responded) == 0 {
+			if err := r.archive.save(snapshotFailedRun, fetchedAt, nil); err != nil {
+				log.Errorf("Unable to archive failed run snapshot - %v", err)
+			}
+		}
+		r.archive.prune(fetchedAt)
+	}
+	if len(responded) == 0 {
+		return nil, nil, fetchedAt, errCannotLoadData
+	}
+
+	merged, err := r.merge(responded)
+	if err != nil {
+		return nil, nil, fetchedAt, err
+	}
+	return &merged, names, fetchedAt, nil
+}
+
+// primaryStatus returns the HTTP status of the last fetch from the primary source
+func (r *sourceRegistry) primaryStatus() int {
+	if len(r.sources) == 0 {
+		return 0
+	}
+	return r.sources[0].Health().LastStatus
+}
+
+// health returns health status of every registered source
+func (r *sourceRegistry) health() []SourceHealth {
+	var result []SourceHealth
+	for _, source := range r.sources {
+		result = append(result, source.Health())
+	}
+	return result
+}
+
+// merge combines responses of the sources which responded in a single run. Under the quorum policy the run fails
+// when fewer sources responded than the configured quorum, as no node could reach it and all would be marked offline.
+func (r *sourceRegistry) merge(responses []NodeResponse) (NodeResponse, error) {
+	quorum := r.quorum
+	if r.policy == MergeQuorum {
+		if quorum == 0 {
+			quorum = len(responses)/2 + 1
+		}
+		if len(responses) < quorum {
+			log.Warnf("Only %v node sources responded, quorum of %v was not reached", len(responses), quorum)
+			return nil, errQuorumNotReached
+		}
+	}
+	return mergeNodeResponses(r.policy, quorum, responses), nil
+}
+
+// mergeNodeResponses combines node lists according to the policy. Responses are expected in priority order.
+func mergeNodeResponses(policy string, quorum int, responses []NodeResponse) NodeResponse {
+	merged := make(map[string]NodeDef)
+	seen := make(map[string]int)
+	for _, response := range responses {
+		reported := make(map[string]bool)
+		for _, def := range response {
+			if reported[def.Key] {
+				continue
+			}
+			reported[def.Key] = true
+			seen[def.Key]++
+
+			existing, found := merged[def.Key]
+			switch {
+			case !found:
+				merged[def.Key] = def
+			case policy == MergePrimaryWins:
+				// node already reported by a source with higher priority
+			case def.StartTime > existing.StartTime:
+				merged[def.Key] = def
+			}
+		}
+	}
+
+	result := make(NodeResponse, 0, len(merged))
+	for key, def := range merged {
+		if policy == MergeQuorum && seen[key] < quorum {
+			continue
+		}
+		result = append(result, def)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
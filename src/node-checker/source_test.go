@@ -0,0 +1,49 @@
+package node_checker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSourcesMergeQuorum(t *testing.T) {
+	first := NodeResponse{{Key: "a", StartTime: 10}, {Key: "b", StartTime: 10}}
+	second := NodeResponse{{Key: "a", StartTime: 20}, {Key: "c", StartTime: 10}}
+	third := NodeResponse{{Key: "a", StartTime: 5}, {Key: "b", StartTime: 30}}
+	tests := []struct {
+		name      string
+		quorum    int
+		responses []NodeResponse
+		expected  NodeResponse
+		err       error
+	}{
+		{name: "majority of responding sources", responses: []NodeResponse{first, second, third}, expected: NodeResponse{{Key: "a", StartTime: 20}, {Key: "b", StartTime: 30}}},
+		{name: "majority of two responding sources", responses: []NodeResponse{first, second}, expected: NodeResponse{{Key: "a", StartTime: 20}}},
+		{name: "single responding source", responses: []NodeResponse{second}, expected: second},
+		{name: "configured quorum", quorum: 3, responses: []NodeResponse{first, second, third}, expected: NodeResponse{{Key: "a", StartTime: 20}}},
+		{name: "configured quorum not reached", quorum: 2, responses: []NodeResponse{first}, err: errQuorumNotReached},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			merged, err := NewSources(MergeQuorum, test.quorum).merge(test.responses)
+			if err != test.err {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+			if err == nil && !reflect.DeepEqual(merged, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, merged)
+			}
+		})
+	}
+}
+
+func TestValidMergePolicy(t *testing.T) {
+	for _, policy := range []string{MergeMaxStartTime, MergePrimaryWins, MergeQuorum} {
+		if !validMergePolicy(policy) {
+			t.Errorf("policy %v should be valid", policy)
+		}
+	}
+	for _, policy := range []string{"", "max", "Quorum"} {
+		if validMergePolicy(policy) {
+			t.Errorf("policy %q should not be valid", policy)
+		}
+	}
+}
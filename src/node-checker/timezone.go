@@ -0,0 +1,66 @@
+package node_checker
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// loadLocation loads the IANA timezone, empty name stands for UTC
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errInvalidTimezone
+	}
+	return location, nil
+}
+
+// reportingLocation returns the configured default timezone in which reporting periods are cut
+func reportingLocation() *time.Location {
+	name := viper.GetString("reporting.timezone")
+	location, err := loadLocation(name)
+	if err != nil {
+		log.Errorf("Invalid reporting timezone %v, falling back to UTC", name)
+		return time.UTC
+	}
+	return location
+}
+
+// monthOf returns the month which contains t in the given location
+func monthOf(t time.Time, location *time.Location) YearMonth {
+	year, month, _ := t.In(location).Date()
+	return YearMonth{Year: year, Month: month}
+}
+
+// startOfDay returns midnight of the day which contains t in the given location. Days are not assumed
+// to be 24 hours long, so the boundaries stay on midnight across DST transitions. When clocks skip
+// midnight, the day starts at the transition.
+func startOfDay(t time.Time, location *time.Location) time.Time {
+	year, month, day := t.In(location).Date()
+	start := time.Date(year, month, day, 0, 0, 0, 0, location)
+	for start.Day() != day {
+		start = start.Add(15 * time.Minute)
+	}
+	return start
+}
+
+// reportingMonth parses the month (YYYY-MM) and the timezone given on the command line. The configured
+// timezone is used when it is empty and the previous month in that timezone when month is empty.
+func reportingMonth(month string, timezone string) (YearMonth, *time.Location, error) {
+	location := reportingLocation()
+	if timezone != "" {
+		var err error
+		if location, err = loadLocation(timezone); err != nil {
+			return YearMonth{}, nil, err
+		}
+	}
+	if month == "" {
+		return monthOf(time.Now(), location).previous(), location, nil
+	}
+	period, err := parseYearMonth(month)
+	return period, location, err
+}
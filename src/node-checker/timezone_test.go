@@ -0,0 +1,108 @@
+package node_checker
+
+import (
+	"testing"
+	"time"
+)
+
+func loadTestLocation(t *testing.T, name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone %v is not available", name)
+	}
+	return location
+}
+
+func TestStartOfDayAcrossDST(t *testing.T) {
+	tests := []struct {
+		zone     string
+		t        time.Time
+		expected time.Time
+		length   time.Duration
+	}{
+		// Europe switches to summer time at 01:00 UTC on the last Sunday of March and back in October
+		{zone: "Europe/Berlin", t: time.Date(2026, time.March, 29, 1, 30, 0, 0, time.UTC), expected: time.Date(2026, time.March, 28, 23, 0, 0, 0, time.UTC), length: 23 * time.Hour},
+		{zone: "Europe/Berlin", t: time.Date(2026, time.October, 25, 22, 59, 0, 0, time.UTC), expected: time.Date(2026, time.October, 24, 22, 0, 0, 0, time.UTC), length: 25 * time.Hour},
+		{zone: "Europe/London", t: time.Date(2026, time.March, 29, 0, 30, 0, 0, time.UTC), expected: time.Date(2026, time.March, 29, 0, 0, 0, 0, time.UTC), length: 23 * time.Hour},
+		{zone: "Europe/London", t: time.Date(2026, time.October, 25, 23, 30, 0, 0, time.UTC), expected: time.Date(2026, time.October, 24, 23, 0, 0, 0, time.UTC), length: 25 * time.Hour},
+		// United States switch on the second Sunday of March and the first Sunday of November at 02:00 local time
+		{zone: "America/New_York", t: time.Date(2026, time.March, 8, 12, 0, 0, 0, time.UTC), expected: time.Date(2026, time.March, 8, 5, 0, 0, 0, time.UTC), length: 23 * time.Hour},
+		{zone: "America/New_York", t: time.Date(2026, time.November, 2, 4, 30, 0, 0, time.UTC), expected: time.Date(2026, time.November, 1, 4, 0, 0, 0, time.UTC), length: 25 * time.Hour},
+		{zone: "America/Los_Angeles", t: time.Date(2026, time.November, 1, 9, 30, 0, 0, time.UTC), expected: time.Date(2026, time.November, 1, 7, 0, 0, 0, time.UTC), length: 25 * time.Hour},
+	}
+	for _, test := range tests {
+		location := loadTestLocation(t, test.zone)
+		start := startOfDay(test.t, location)
+		if !start.Equal(test.expected) {
+			t.Errorf("%v: expected day of %v to start at %v, got %v", test.zone, test.t, test.expected, start.UTC())
+		}
+		if hour, min, _ := start.In(location).Clock(); hour != 0 || min != 0 {
+			t.Errorf("%v: day of %v does not start at midnight, got %v", test.zone, test.t, start)
+		}
+		if length := nextBucket(start, GranularityDay, location).Sub(start); length != test.length {
+			t.Errorf("%v: expected day of %v to last %v, got %v", test.zone, test.t, test.length, length)
+		}
+	}
+}
+
+func TestStartOfDayWithoutMidnight(t *testing.T) {
+	// Chile switches to summer time at midnight, so 2026-09-06 has no 00:00
+	santiago := loadTestLocation(t, "America/Santiago")
+	for _, hour := range []int{1, 12, 23} {
+		moment := time.Date(2026, time.September, 6, hour, 0, 0, 0, santiago)
+		start := startOfDay(moment, santiago)
+		if start.After(moment) || start.In(santiago).Day() != 6 {
+			t.Errorf("expected day of %v to start on the same day before it, got %v", moment, start)
+		}
+		if next := nextBucket(start.UTC(), GranularityDay, santiago); !next.After(moment) {
+			t.Errorf("expected the next day to start after %v, got %v", moment, next)
+		}
+	}
+}
+
+func TestMonthOfAcrossDST(t *testing.T) {
+	tests := []struct {
+		zone     string
+		t        time.Time
+		expected YearMonth
+	}{
+		{zone: "UTC", t: time.Date(2026, time.October, 31, 23, 30, 0, 0, time.UTC), expected: YearMonth{Year: 2026, Month: time.October}},
+		// last hour of October in Berlin is already winter time, one hour ahead of UTC
+		{zone: "Europe/Berlin", t: time.Date(2026, time.October, 31, 22, 59, 0, 0, time.UTC), expected: YearMonth{Year: 2026, Month: time.October}},
+		{zone: "Europe/Berlin", t: time.Date(2026, time.October, 31, 23, 0, 0, 0, time.UTC), expected: YearMonth{Year: 2026, Month: time.November}},
+		// first hour of April in Berlin is summer time, two hours ahead of UTC
+		{zone: "Europe/Berlin", t: time.Date(2026, time.March, 31, 21, 59, 0, 0, time.UTC), expected: YearMonth{Year: 2026, Month: time.March}},
+		{zone: "Europe/Berlin", t: time.Date(2026, time.March, 31, 22, 0, 0, 0, time.UTC), expected: YearMonth{Year: 2026, Month: time.April}},
+		// New York is five hours behind UTC in November after the switch and four hours behind in October
+		{zone: "America/New_York", t: time.Date(2026, time.November, 1, 3, 59, 0, 0, time.UTC), expected: YearMonth{Year: 2026, Month: time.October}},
+		{zone: "America/New_York", t: time.Date(2026, time.November, 1, 4, 0, 0, 0, time.UTC), expected: YearMonth{Year: 2026, Month: time.November}},
+		{zone: "America/New_York", t: time.Date(2026, time.December, 1, 4, 59, 0, 0, time.UTC), expected: YearMonth{Year: 2026, Month: time.November}},
+		{zone: "America/New_York", t: time.Date(2026, time.December, 1, 5, 0, 0, 0, time.UTC), expected: YearMonth{Year: 2026, Month: time.December}},
+	}
+	for _, test := range tests {
+		location := loadTestLocation(t, test.zone)
+		if actual := monthOf(test.t, location); actual != test.expected {
+			t.Errorf("%v: expected %v to be in %v, got %v", test.zone, test.t, test.expected, actual)
+		}
+		month := monthOf(test.t, location)
+		if test.t.Before(month.start(location)) || !test.t.Before(month.end(location)) {
+			t.Errorf("%v: %v is not within its month %v", test.zone, test.t, month)
+		}
+	}
+}
+
+func TestHourAligned(t *testing.T) {
+	moment := time.Date(2026, time.March, 29, 12, 0, 0, 0, time.UTC)
+	tests := map[string]bool{
+		"UTC":              true,
+		"Europe/Berlin":    true,
+		"America/New_York": true,
+		"Asia/Kolkata":     false,
+		"Asia/Kathmandu":   false,
+	}
+	for zone, expected := range tests {
+		if actual := hourAligned(moment, loadTestLocation(t, zone)); actual != expected {
+			t.Errorf("%v: expected hour alignment %v, got %v", zone, expected, actual)
+		}
+	}
+}